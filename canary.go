@@ -0,0 +1,244 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Canary shifts traffic of an HTTP upstream from stable servers to canary
+// servers in steps. Each step is the share of requests, in percent, that
+// the canary servers get. After setting the weights of a step, the canary
+// waits for the pause configured with WithPause and compares the 5xx
+// response rate of the canary servers with the rate configured
+// with WithMaxErrorRate. When the rate is exceeded, or a step fails,
+// the canary restores the weights all servers had before it started,
+// even when it stops because its context is done.
+type Canary struct {
+	client   *Client
+	upstream string
+	servers  []string
+	steps    []int
+	cfg      rolloutConfig
+}
+
+// NewCanary takes the client, the upstream, addresses of its canary servers
+// and the steps, and constructs a new canary. Steps must be increasing
+// and between 1 and 99. The canary requires the WithPause
+// and WithMaxErrorRate options. It always restores the previous weights
+// when it stops and doesn't wait for peers to become healthy, so it rejects
+// the WithRollback and WithHealthTimeout options.
+func NewCanary(c *Client, upstream string, servers []string, steps []int, opts ...rolloutOption) (*Canary, error) {
+	if c == nil {
+		return nil, errors.New("nil client")
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("no canary servers")
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("no canary steps")
+	}
+	for i, step := range steps {
+		if step < 1 || step > 99 {
+			return nil, fmt.Errorf("canary step %d must be between 1 and 99", step)
+		}
+		if i > 0 && step <= steps[i-1] {
+			return nil, errors.New("canary steps must be increasing")
+		}
+	}
+	var cfg rolloutConfig
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.pause == 0 || !cfg.checkErrors {
+		return nil, errors.New("canary requires a pause and a max error rate")
+	}
+	if cfg.rollback || cfg.healthTimeout != 0 {
+		return nil, errors.New("canary doesn't support rollback and health timeout options")
+	}
+	return &Canary{
+		client:   c,
+		upstream: upstream,
+		servers:  servers,
+		steps:    steps,
+		cfg:      cfg,
+	}, nil
+}
+
+// Run goes through the steps of the canary. It returns nil when the canary
+// servers get the share of the last step without exceeding the error rate.
+// Otherwise it returns the error, which wraps ErrErrorRateExceeded
+// on a regression, and restores the previous weights.
+func (cn *Canary) Run(ctx context.Context) error {
+	previous, err := cn.client.GetHTTPServers(ctx, cn.upstream)
+	if err != nil {
+		return fmt.Errorf("canary of %v upstream: %w", cn.upstream, err)
+	}
+	canary, stable, err := cn.split(previous)
+	if err != nil {
+		return fmt.Errorf("canary of %v upstream: %w", cn.upstream, err)
+	}
+	for _, step := range cn.steps {
+		if err := cn.step(ctx, canary, stable, step); err != nil {
+			return cn.abort(ctx, step, err, previous)
+		}
+	}
+	return nil
+}
+
+// split divides servers of the upstream into canary and stable servers.
+func (cn *Canary) split(servers []UpstreamServer) (canary, stable []UpstreamServer, err error) {
+	port := cn.client.serverPort()
+	wanted := make(map[string]bool, len(cn.servers))
+	for _, server := range cn.servers {
+		wanted[normalizeServer(server, port)] = true
+	}
+	for _, server := range servers {
+		if wanted[normalizeServer(server.Server, port)] {
+			canary = append(canary, server)
+			continue
+		}
+		stable = append(stable, server)
+	}
+	if len(canary) != len(wanted) {
+		return nil, nil, fmt.Errorf("%d of %d canary servers: %w", len(canary), len(wanted), ErrServerNotFound)
+	}
+	if len(stable) == 0 {
+		return nil, nil, errors.New("no stable servers")
+	}
+	return canary, stable, nil
+}
+
+// step sets the weights that give canary servers the share of requests
+// in percent, waits for the pause and checks the canary error rate.
+// The weights of the canary and stable servers are changed in the order
+// that gives the canary servers the smaller share of requests in between,
+// so when the share grows, stable weights are lowered first.
+func (cn *Canary) step(ctx context.Context, canary, stable []UpstreamServer, share int) error {
+	canaryWeight, stableWeight := canaryWeights(share, len(canary), len(stable))
+	oldCanary, oldStable := totalWeight(canary), totalWeight(stable)
+	newCanary, newStable := canaryWeight*len(canary), stableWeight*len(stable)
+	if oldCanary*oldStable <= newCanary*newStable {
+		if err := cn.setWeights(ctx, stable, stableWeight); err != nil {
+			return err
+		}
+		if err := cn.setWeights(ctx, canary, canaryWeight); err != nil {
+			return err
+		}
+	} else {
+		if err := cn.setWeights(ctx, canary, canaryWeight); err != nil {
+			return err
+		}
+		if err := cn.setWeights(ctx, stable, stableWeight); err != nil {
+			return err
+		}
+	}
+
+	before, err := cn.canaryPeers(ctx)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cn.client.clock().After(cn.cfg.pause):
+	}
+	after, err := cn.canaryPeers(ctx)
+	if err != nil {
+		return err
+	}
+	if rate := errorRate(before, after); rate > cn.cfg.maxErrorRate {
+		return fmt.Errorf("canary 5xx rate %.4f: %w", rate, ErrErrorRateExceeded)
+	}
+	return nil
+}
+
+// canaryWeights returns the weights of each canary and stable server,
+// that give the canary servers the share of requests in percent.
+func canaryWeights(share, canaryServers, stableServers int) (canaryWeight, stableWeight int) {
+	canaryWeight = share * stableServers
+	stableWeight = (100 - share) * canaryServers
+	d := gcd(canaryWeight, stableWeight)
+	return canaryWeight / d, stableWeight / d
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// totalWeight returns the sum of weights of the servers.
+func totalWeight(servers []UpstreamServer) int {
+	var total int
+	for _, server := range servers {
+		total += weightOf(server)
+	}
+	return total
+}
+
+func weightOf(server UpstreamServer) int {
+	if server.Weight == nil {
+		return defaultWeight
+	}
+	return *server.Weight
+}
+
+// setWeights sets the weight of the servers and records it in the slice,
+// so the next step knows the current weights.
+func (cn *Canary) setWeights(ctx context.Context, servers []UpstreamServer, weight int) error {
+	for i := range servers {
+		if err := cn.setWeight(ctx, servers[i], weight); err != nil {
+			return err
+		}
+		servers[i].Weight = &weight
+	}
+	return nil
+}
+
+func (cn *Canary) setWeight(ctx context.Context, server UpstreamServer, weight int) error {
+	return cn.client.UpdateHTTPServer(ctx, cn.upstream, UpstreamServer{
+		ID:     server.ID,
+		Server: server.Server,
+		Weight: &weight,
+	})
+}
+
+// canaryPeers returns the upstream stats with only the canary server peers.
+func (cn *Canary) canaryPeers(ctx context.Context) (Upstream, error) {
+	u, err := cn.client.GetUpstream(ctx, cn.upstream)
+	if err != nil {
+		return Upstream{}, err
+	}
+	port := cn.client.serverPort()
+	wanted := make(map[string]bool, len(cn.servers))
+	for _, server := range cn.servers {
+		wanted[normalizeServer(server, port)] = true
+	}
+	var peers []Peer
+	for _, p := range u.Peers {
+		if wanted[normalizeServer(p.Server, port)] {
+			peers = append(peers, p)
+		}
+	}
+	u.Peers = peers
+	return u, nil
+}
+
+// abort builds the error of a stopped canary and restores the weights
+// the servers had before the canary started. The weights are restored
+// even if ctx is done, within the rollback timeout.
+func (cn *Canary) abort(ctx context.Context, step int, err error, previous []UpstreamServer) error {
+	errs := []error{fmt.Errorf("canary of %v upstream stopped at %d%%: %w", cn.upstream, step, err)}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	for _, server := range previous {
+		if rerr := cn.setWeight(ctx, server, weightOf(server)); rerr != nil {
+			errs = append(errs, fmt.Errorf("restoring weight of %v server: %w", server.Server, rerr))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,169 @@
+package ngx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestCanary_ShiftsWeightsToCanaryServersInSteps(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t,
+		ngx.UpstreamServer{Server: "10.0.0.1:80"},
+		ngx.UpstreamServer{Server: "10.0.0.2:80"},
+		ngx.UpstreamServer{Server: "10.0.0.3:80"},
+	)
+
+	c := newNginxTestClient(ts.URL, t)
+	cn, err := ngx.NewCanary(c, "test", []string{"10.0.0.3"}, []int{10, 25},
+		ngx.WithPause(time.Millisecond), ngx.WithMaxErrorRate(0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cn.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"10.0.0.1:80": 3, "10.0.0.2:80": 3, "10.0.0.3:80": 2}
+	if got := f.Weights(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestCanary_RestoresWeightsWhenCanaryErrorRateIsExceeded(t *testing.T) {
+	t.Parallel()
+	weight := 4
+	f, ts := newFakeUpstream("up", t,
+		ngx.UpstreamServer{Server: "10.0.0.1:80", Weight: &weight},
+		ngx.UpstreamServer{Server: "10.0.0.2:80"},
+	)
+	f.Fail("10.0.0.2:80")
+
+	c := newNginxTestClient(ts.URL, t)
+	cn, err := ngx.NewCanary(c, "test", []string{"10.0.0.2:80"}, []int{10, 50},
+		ngx.WithPause(time.Millisecond), ngx.WithMaxErrorRate(0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cn.Run(context.Background())
+	if !errors.Is(err, ngx.ErrErrorRateExceeded) {
+		t.Fatalf("want ErrErrorRateExceeded, got %v", err)
+	}
+	want := map[string]int{"10.0.0.1:80": 4, "10.0.0.2:80": 1}
+	if got := f.Weights(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestCanary_FailsOnUnknownCanaryServer(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	cn, err := ngx.NewCanary(c, "test", []string{"10.0.0.9:80"}, []int{10},
+		ngx.WithPause(time.Millisecond), ngx.WithMaxErrorRate(0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cn.Run(context.Background()); !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestNewCanary_FailsOnInvalidConfiguration(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://127.0.0.1:0", t)
+	pause, rate := ngx.WithPause(time.Millisecond), ngx.WithMaxErrorRate(0.01)
+	tests := map[string]func() (*ngx.Canary, error){
+		"no servers":        func() (*ngx.Canary, error) { return ngx.NewCanary(c, "test", nil, []int{10}, pause, rate) },
+		"no steps":          func() (*ngx.Canary, error) { return ngx.NewCanary(c, "test", []string{"a"}, nil, pause, rate) },
+		"step out of range": func() (*ngx.Canary, error) { return ngx.NewCanary(c, "test", []string{"a"}, []int{100}, pause, rate) },
+		"decreasing steps": func() (*ngx.Canary, error) {
+			return ngx.NewCanary(c, "test", []string{"a"}, []int{50, 10}, pause, rate)
+		},
+		"missing pause":          func() (*ngx.Canary, error) { return ngx.NewCanary(c, "test", []string{"a"}, []int{10}, rate) },
+		"missing max error rate": func() (*ngx.Canary, error) { return ngx.NewCanary(c, "test", []string{"a"}, []int{10}, pause) },
+		"rollback": func() (*ngx.Canary, error) {
+			return ngx.NewCanary(c, "test", []string{"a"}, []int{10}, pause, rate, ngx.WithRollback())
+		},
+		"health timeout": func() (*ngx.Canary, error) {
+			return ngx.NewCanary(c, "test", []string{"a"}, []int{10}, pause, rate, ngx.WithHealthTimeout(time.Second))
+		},
+	}
+	for name, newCanary := range tests {
+		if _, err := newCanary(); err == nil {
+			t.Errorf("%s: want error, got nil", name)
+		}
+	}
+}
+
+func TestCanary_RestoresWeightsWhenContextIsDoneDuringPause(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t,
+		ngx.UpstreamServer{Server: "10.0.0.1:80"},
+		ngx.UpstreamServer{Server: "10.0.0.2:80"},
+	)
+
+	c := newNginxTestClient(ts.URL, t)
+	cn, err := ngx.NewCanary(c, "test", []string{"10.0.0.2:80"}, []int{10},
+		ngx.WithPause(time.Minute), ngx.WithMaxErrorRate(0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cn.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+	want := map[string]int{"10.0.0.1:80": 1, "10.0.0.2:80": 1}
+	if got := f.Weights(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestCanary_NeverGivesCanaryMoreThanStepShareWhileChangingWeights(t *testing.T) {
+	t.Parallel()
+	f, _ := newFakeUpstream("up", t,
+		ngx.UpstreamServer{Server: "10.0.0.1:80"},
+		ngx.UpstreamServer{Server: "10.0.0.2:80"},
+		ngx.UpstreamServer{Server: "10.0.0.3:80"},
+	)
+	var mu sync.Mutex
+	var maxShare float64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.ServeHTTP(w, r)
+		if r.Method != http.MethodPatch {
+			return
+		}
+		weights := map[string]int{"10.0.0.1:80": 1, "10.0.0.2:80": 1, "10.0.0.3:80": 1}
+		for server, weight := range f.Weights() {
+			weights[server] = weight
+		}
+		total := weights["10.0.0.1:80"] + weights["10.0.0.2:80"] + weights["10.0.0.3:80"]
+		mu.Lock()
+		defer mu.Unlock()
+		maxShare = max(maxShare, float64(weights["10.0.0.3:80"])/float64(total))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	cn, err := ngx.NewCanary(c, "test", []string{"10.0.0.3:80"}, []int{10, 25},
+		ngx.WithPause(time.Millisecond), ngx.WithMaxErrorRate(0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cn.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxShare > 0.25 {
+		t.Errorf("want canary share at most 25%%, got %.2f", maxShare)
+	}
+}
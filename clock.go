@@ -0,0 +1,54 @@
+package ngx
+
+import "time"
+
+// Clock provides the current time, tickers and timers to the time dependent
+// parts of the Client, like polling stats, backoff, rollout pauses and
+// timestamps of journal entries and samples. Replacing the Clock allows
+// to test code built on the Client without real sleeps.
+// Timeouts given to the Client methods are always measured in real time.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks of a Clock at intervals.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// clock returns the Clock of the Client.
+func (c Client) clock() Clock {
+	if c.clk == nil {
+		return realClock{}
+	}
+	return c.clk
+}
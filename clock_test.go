@@ -0,0 +1,91 @@
+package ngx_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qba73/ngx"
+)
+
+// instantClock is a Clock that is stopped at the given time
+// and whose tickers and timers fire immediately.
+type instantClock struct {
+	now time.Time
+}
+
+func (c instantClock) Now() time.Time {
+	return c.now
+}
+
+func (c instantClock) NewTicker(time.Duration) ngx.Ticker {
+	return instantTicker{}
+}
+
+func (c instantClock) After(time.Duration) <-chan time.Time {
+	return fired
+}
+
+type instantTicker struct{}
+
+func (instantTicker) C() <-chan time.Time {
+	return fired
+}
+
+func (instantTicker) Stop() {}
+
+var fired = func() chan time.Time {
+	ch := make(chan time.Time)
+	close(ch)
+	return ch
+}()
+
+func TestMonitorDrain_PollsAtClockTicks(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.SetActive(5, 4, 3, 2, 1, 0)
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithClock(instantClock{}), ngx.WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var polls int
+	for status := range c.MonitorDrain(ctx, "test", "10.0.0.1:80") {
+		if status.Err != nil {
+			t.Fatal(status.Err)
+		}
+		polls++
+	}
+	if polls != 6 {
+		t.Errorf("want 6 polls, got %d", polls)
+	}
+}
+
+func TestFileJournal_RecordsEntriesWithClockTime(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t)
+	now := time.Date(2023, 4, 17, 10, 0, 0, 0, time.UTC)
+
+	journal, err := ngx.NewFileJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ngx.NewClient(ts.URL, ngx.WithJournal(journal), ngx.WithClock(instantClock{now: now}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Time.Equal(now) {
+		t.Errorf("want single entry recorded at %v, got %v", now, entries)
+	}
+}
@@ -0,0 +1,311 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ClusterClient lets you manage a cluster of NGINX Plus instances
+// that must keep dynamically configured upstreams identical.
+type ClusterClient struct {
+	Clients []*Client
+}
+
+// NewClusterClient takes clients of all cluster members
+// and constructs a new cluster client.
+func NewClusterClient(clients ...*Client) (*ClusterClient, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no cluster members")
+	}
+	for _, c := range clients {
+		if c == nil {
+			return nil, errors.New("nil cluster member client")
+		}
+	}
+	return &ClusterClient{Clients: clients}, nil
+}
+
+// InstanceResult represents the result of updating servers
+// of an upstream on a single cluster member.
+type InstanceResult struct {
+	URL     string
+	Added   []UpstreamServer
+	Deleted []UpstreamServer
+	Updated []UpstreamServer
+	Err     error
+}
+
+// UpdateHTTPServers updates the servers of the upstream on all cluster members
+// concurrently. It returns the results for each member, in the order of
+// the cluster clients, and a joined error of all members that failed.
+// The options are applied to the update of every member.
+func (cc *ClusterClient) UpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...updateOption) ([]InstanceResult, error) {
+	results := make([]InstanceResult, len(cc.Clients))
+	var wg sync.WaitGroup
+	for i, c := range cc.Clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			added, deleted, updated, err := c.UpdateHTTPServers(ctx, upstream, servers, opts...)
+			results[i] = InstanceResult{
+				URL:     c.URL,
+				Added:   added,
+				Deleted: deleted,
+				Updated: updated,
+				Err:     err,
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	if err := joinInstanceErrors(results); err != nil {
+		return results, fmt.Errorf("updating servers of %v upstream in cluster: %w", upstream, err)
+	}
+	return results, nil
+}
+
+func joinInstanceErrors(results []InstanceResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", r.URL, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+const defaultHealthTimeout = 30 * time.Second
+
+// rollbackTimeout bounds restoring the previous state of an upstream after
+// a failed rollout, which runs even when the rollout's context is done.
+const rollbackTimeout = 30 * time.Second
+
+var (
+	// ErrUnhealthyUpstream is returned when peers of an upstream
+	// don't become healthy in the configured time.
+	ErrUnhealthyUpstream = errors.New("upstream is not healthy")
+
+	// ErrErrorRateExceeded is returned when the 5xx response rate
+	// of an upstream exceeds the configured maximum during a rollout.
+	ErrErrorRateExceeded = errors.New("upstream error rate exceeded")
+)
+
+type rolloutConfig struct {
+	rollback      bool
+	healthTimeout time.Duration
+	pause         time.Duration
+	maxErrorRate  float64
+	checkErrors   bool
+}
+
+// rolloutOption helps to configure rolling updates of cluster members.
+type rolloutOption func(*rolloutConfig) error
+
+// WithRollback is a func option that configures the rolling update
+// to restore the previous servers on all already updated cluster members
+// when the update of a member fails or its upstream doesn't become healthy.
+func WithRollback() rolloutOption {
+	return func(cfg *rolloutConfig) error {
+		cfg.rollback = true
+		return nil
+	}
+}
+
+// WithHealthTimeout is a func option that configures how long the rolling
+// update waits for peers of the updated upstream to become healthy
+// before it gives up. The default timeout is 30 seconds.
+func WithHealthTimeout(d time.Duration) rolloutOption {
+	return func(cfg *rolloutConfig) error {
+		if d <= 0 {
+			return errors.New("health timeout must be positive")
+		}
+		cfg.healthTimeout = d
+		return nil
+	}
+}
+
+// WithPause is a func option that configures the rolling update to wait
+// for the given time after updating a cluster member, before it proceeds
+// to the next one, so changes don't hit the whole cluster at once.
+func WithPause(d time.Duration) rolloutOption {
+	return func(cfg *rolloutConfig) error {
+		if d <= 0 {
+			return errors.New("pause must be positive")
+		}
+		cfg.pause = d
+		return nil
+	}
+}
+
+// WithMaxErrorRate is a func option that configures the rolling update to
+// measure the ratio of 5xx responses to all responses of the updated upstream
+// during the pause after each member, and to stop when the ratio exceeds
+// the given rate. The rate is a value between 0 and 1.
+// The option requires the pause to be configured with WithPause.
+func WithMaxErrorRate(rate float64) rolloutOption {
+	return func(cfg *rolloutConfig) error {
+		if rate < 0 || rate > 1 {
+			return errors.New("error rate must be between 0 and 1")
+		}
+		cfg.maxErrorRate = rate
+		cfg.checkErrors = true
+		return nil
+	}
+}
+
+// RollingUpdateHTTPServers updates the servers of the upstream on cluster members
+// one by one. After updating a member it waits until all peers of the upstream
+// are up, as reported by the upstream peer stats, before it proceeds to
+// the next member. The update stops at the first member that fails
+// or doesn't become healthy.
+func (cc *ClusterClient) RollingUpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...rolloutOption) ([]InstanceResult, error) {
+	cfg := rolloutConfig{
+		healthTimeout: defaultHealthTimeout,
+	}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.checkErrors && cfg.pause == 0 {
+		return nil, errors.New("checking error rate requires a pause")
+	}
+
+	var results []InstanceResult
+	var previous [][]UpstreamServer
+	for i, c := range cc.Clients {
+		prev, err := c.GetHTTPServers(ctx, upstream)
+		if err != nil {
+			results = append(results, InstanceResult{URL: c.URL, Err: err})
+			return results, cc.abortRollout(ctx, upstream, c.URL, err, cfg, previous)
+		}
+		previous = append(previous, prev)
+
+		added, deleted, updated, err := c.UpdateHTTPServers(ctx, upstream, servers)
+		if err == nil {
+			err = c.waitForHealthyUpstream(ctx, upstream, cfg.healthTimeout)
+		}
+		if err == nil && cfg.pause > 0 && (cfg.checkErrors || i < len(cc.Clients)-1) {
+			err = c.pace(ctx, upstream, cfg)
+		}
+		results = append(results, InstanceResult{
+			URL:     c.URL,
+			Added:   added,
+			Deleted: deleted,
+			Updated: updated,
+			Err:     err,
+		})
+		if err != nil {
+			return results, cc.abortRollout(ctx, upstream, c.URL, err, cfg, previous)
+		}
+	}
+	return results, nil
+}
+
+// abortRollout builds the error of a stopped rolling update and, if configured,
+// restores the previous servers on the cluster members updated so far.
+// The servers are restored even if ctx is done, within the rollback timeout.
+func (cc *ClusterClient) abortRollout(ctx context.Context, upstream, url string, err error, cfg rolloutConfig, previous [][]UpstreamServer) error {
+	err = fmt.Errorf("rolling update of %v upstream stopped at %v: %w", upstream, url, err)
+	if !cfg.rollback {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	errs := []error{err}
+	for i, prev := range previous {
+		servers := make([]UpstreamServer, 0, len(prev))
+		for _, s := range prev {
+			s.ID = 0
+			servers = append(servers, s)
+		}
+		c := cc.Clients[i]
+		if _, _, _, rerr := c.UpdateHTTPServers(ctx, upstream, servers); rerr != nil {
+			errs = append(errs, fmt.Errorf("rolling back %v: %w", c.URL, rerr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pace waits for the configured pause and, if configured, verifies
+// the 5xx response rate of the upstream measured during the pause.
+func (c Client) pace(ctx context.Context, upstream string, cfg rolloutConfig) error {
+	var before Upstream
+	if cfg.checkErrors {
+		u, err := c.GetUpstream(ctx, upstream)
+		if err != nil {
+			return err
+		}
+		before = u
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.clock().After(cfg.pause):
+	}
+	if !cfg.checkErrors {
+		return nil
+	}
+	after, err := c.GetUpstream(ctx, upstream)
+	if err != nil {
+		return err
+	}
+	if rate := errorRate(before, after); rate > cfg.maxErrorRate {
+		return fmt.Errorf("%v upstream 5xx rate %.4f: %w", upstream, rate, ErrErrorRateExceeded)
+	}
+	return nil
+}
+
+// errorRate returns the ratio of 5xx responses to all responses
+// of the upstream peers between two snapshots of upstream stats.
+func errorRate(before, after Upstream) float64 {
+	total5xx := func(u Upstream) (errs, total uint64) {
+		for _, p := range u.Peers {
+			errs += p.Responses.Responses5xx
+			total += p.Responses.Total
+		}
+		return errs, total
+	}
+	errsBefore, totalBefore := total5xx(before)
+	errsAfter, totalAfter := total5xx(after)
+	if totalAfter <= totalBefore || errsAfter < errsBefore {
+		return 0
+	}
+	return float64(errsAfter-errsBefore) / float64(totalAfter-totalBefore)
+}
+
+// waitForHealthyUpstream polls upstream stats until all peers of the upstream,
+// that are not administratively down or draining, are up.
+func (c Client) waitForHealthyUpstream(ctx context.Context, upstream string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := c.clock().NewTicker(c.interval())
+	defer ticker.Stop()
+	for {
+		u, err := c.GetUpstream(ctx, upstream)
+		if err == nil && isHealthy(u.Peers) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("waiting for %v upstream: %w", upstream, err)
+			}
+			return fmt.Errorf("waiting for %v upstream: %w", upstream, ErrUnhealthyUpstream)
+		case <-ticker.C():
+		}
+	}
+}
+
+func isHealthy(peers []Peer) bool {
+	for _, p := range peers {
+		switch p.State {
+		case "up", "down", "draining":
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package ngx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+// newUpstreamTestServer returns a test server that reports no servers
+// in the upstream and counts the servers added to it.
+func newUpstreamTestServer(added *int32, t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[]`))
+		case http.MethodPost:
+			atomic.AddInt32(added, 1)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+func TestClusterClient_UpdateHTTPServersAppliesServersToAllMembers(t *testing.T) {
+	t.Parallel()
+	var added int32
+	ts1 := newUpstreamTestServer(&added, t)
+	defer ts1.Close()
+	ts2 := newUpstreamTestServer(&added, t)
+	defer ts2.Close()
+
+	cc, err := ngx.NewClusterClient(newNginxTestClient(ts1.URL, t), newNginxTestClient(ts2.URL, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}}
+	results, err := cc.UpdateHTTPServers(context.Background(), "test", servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("want 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if len(r.Added) != 2 {
+			t.Errorf("%s: want 2 added servers, got %d", r.URL, len(r.Added))
+		}
+	}
+	if added := atomic.LoadInt32(&added); added != 4 {
+		t.Errorf("want 4 servers added in cluster, got %d", added)
+	}
+}
+
+func TestClusterClient_UpdateHTTPServersReportsFailedMembers(t *testing.T) {
+	t.Parallel()
+	var added int32
+	ts := newUpstreamTestServer(&added, t)
+	defer ts.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	cc, err := ngx.NewClusterClient(newNginxTestClient(ts.URL, t), newNginxTestClient(failing.URL, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := cc.UpdateHTTPServers(context.Background(), "test", []ngx.UpstreamServer{{Server: "10.0.0.1:80"}})
+	if err == nil {
+		t.Fatal("want error on failed cluster member, got nil")
+	}
+	if results[0].Err != nil {
+		t.Errorf("want no error for healthy member, got %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("want error for failing member, got nil")
+	}
+}
+
+func TestClusterClient_RollingUpdateHTTPServersUpdatesHealthyMembers(t *testing.T) {
+	t.Parallel()
+	f1, ts1 := newFakeUpstream("up", t)
+	f2, ts2 := newFakeUpstream("up", t)
+
+	cc, err := ngx.NewClusterClient(newNginxTestClient(ts1.URL, t), newNginxTestClient(ts2.URL, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1:80"}}
+	if _, err := cc.RollingUpdateHTTPServers(context.Background(), "test", servers); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1:80"}
+	for _, f := range []*fakeUpstream{f1, f2} {
+		if got := f.Servers(); !cmp.Equal(want, got) {
+			t.Error(cmp.Diff(want, got))
+		}
+	}
+}
+
+func TestClusterClient_RollingUpdateHTTPServersStopsAndRollsBackOnUnhealthyMember(t *testing.T) {
+	t.Parallel()
+	f1, ts1 := newFakeUpstream("unhealthy", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f2, ts2 := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	cc, err := ngx.NewClusterClient(newNginxTestClient(ts1.URL, t), newNginxTestClient(ts2.URL, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	_, err = cc.RollingUpdateHTTPServers(context.Background(), "test", servers,
+		ngx.WithHealthTimeout(50*time.Millisecond),
+		ngx.WithRollback(),
+	)
+	if !errors.Is(err, ngx.ErrUnhealthyUpstream) {
+		t.Fatalf("want ErrUnhealthyUpstream, got %v", err)
+	}
+	want := []string{"10.0.0.1:80"}
+	if got := f1.Servers(); !cmp.Equal(want, got) {
+		t.Errorf("want first member rolled back, %s", cmp.Diff(want, got))
+	}
+	if f2.Mutations() != 0 {
+		t.Error("want second member untouched")
+	}
+}
+
+func TestClusterClient_RollingUpdateHTTPServersRollsBackWhenContextIsDoneMidRollout(t *testing.T) {
+	t.Parallel()
+	f1, ts1 := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f2, ts2 := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	cc, err := ngx.NewClusterClient(newNginxTestClient(ts1.URL, t), newNginxTestClient(ts2.URL, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	_, err = cc.RollingUpdateHTTPServers(ctx, "test", servers,
+		ngx.WithPause(time.Minute),
+		ngx.WithRollback(),
+	)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+	want := []string{"10.0.0.1:80"}
+	if got := f1.Servers(); !cmp.Equal(want, got) {
+		t.Errorf("want first member rolled back, %s", cmp.Diff(want, got))
+	}
+	if f2.Mutations() != 0 {
+		t.Error("want second member untouched")
+	}
+}
+
+func TestClusterClient_RollingUpdateHTTPServersFailsOnErrorRateWithoutPause(t *testing.T) {
+	t.Parallel()
+	cc, err := ngx.NewClusterClient(newNginxTestClient("http://localhost", t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cc.RollingUpdateHTTPServers(context.Background(), "test", nil, ngx.WithMaxErrorRate(0.01))
+	if err == nil {
+		t.Fatal("want error on error rate check without pause, got nil")
+	}
+}
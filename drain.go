@@ -0,0 +1,129 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DrainStatus represents the progress of draining a server of an upstream.
+// Active is the number of active connections of the server peers.
+type DrainStatus struct {
+	Active uint64
+	Err    error
+}
+
+// MonitorDrain polls the upstream stats and delivers on the returned channel
+// the number of active connections of the draining server. The channel is
+// closed after the server has no active connections, after an error
+// is delivered, or when ctx is done.
+func (c Client) MonitorDrain(ctx context.Context, upstream, server string) <-chan DrainStatus {
+	ch := make(chan DrainStatus)
+	go func() {
+		defer close(ch)
+		ticker := c.clock().NewTicker(c.interval())
+		defer ticker.Stop()
+		for {
+			active, err := c.activeConnections(ctx, upstream, server)
+			select {
+			case ch <- DrainStatus{Active: active, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil || active == 0 {
+				return
+			}
+			select {
+			case <-ticker.C():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// WaitForDrain polls the upstream stats every pollInterval until the server
+// has no active connections. It returns the error of reading the stats,
+// or the error of ctx when ctx is done before the server is drained.
+func (c Client) WaitForDrain(ctx context.Context, upstream, server string, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return errors.New("poll interval must be positive")
+	}
+	c.pollInterval = pollInterval
+	for status := range c.MonitorDrain(ctx, upstream, server) {
+		if status.Err != nil {
+			return fmt.Errorf("waiting for %v server of %v upstream to drain: %w", server, upstream, status.Err)
+		}
+		if status.Active == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("waiting for %v server of %v upstream to drain: %w", server, upstream, ctx.Err())
+}
+
+// DrainHTTPServer puts the server of the upstream into the draining mode,
+// so NGINX only sends it requests of already established sessions.
+func (c Client) DrainHTTPServer(ctx context.Context, upstream, server string) error {
+	if err := c.setDrain(ctx, upstream, server, true); err != nil {
+		return fmt.Errorf("draining %v server of %v upstream: %w", server, upstream, err)
+	}
+	return nil
+}
+
+// UndrainHTTPServer takes the server of the upstream out of the draining mode.
+func (c Client) UndrainHTTPServer(ctx context.Context, upstream, server string) error {
+	if err := c.setDrain(ctx, upstream, server, false); err != nil {
+		return fmt.Errorf("undraining %v server of %v upstream: %w", server, upstream, err)
+	}
+	return nil
+}
+
+// setDrain sets the drain flag of the server, found by its normalized
+// address. The flag is sent on its own, because UpstreamServer omits
+// the flag when it's false.
+func (c Client) setDrain(ctx context.Context, upstream, server string, drain bool) error {
+	s, err := c.GetHTTPServerByName(ctx, upstream, server)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, s.ID)
+	if err := c.patch(ctx, path, map[string]bool{"drain": drain}, http.StatusOK); err != nil {
+		return serverError(err)
+	}
+	return nil
+}
+
+// activeConnections returns the number of active connections of all peers
+// of the upstream, that have the given server address or name.
+// Addresses and names are compared by their normalized form.
+func (c Client) activeConnections(ctx context.Context, upstream, server string) (uint64, error) {
+	u, err := c.GetUpstream(ctx, upstream)
+	if err != nil {
+		return 0, err
+	}
+	port := c.serverPort()
+	address := normalizeServer(server, port)
+	var active uint64
+	var found bool
+	for _, p := range u.Peers {
+		if normalizeServer(p.Server, port) == address || normalizeServer(p.Name, port) == address {
+			active += p.Active
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("server %v not found in %v upstream", server, upstream)
+	}
+	return active, nil
+}
+
+// interval returns the interval of polling NGINX stats.
+func (c Client) interval() time.Duration {
+	if c.pollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return c.pollInterval
+}
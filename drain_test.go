@@ -0,0 +1,152 @@
+package ngx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestMonitorDrain_DeliversActiveConnectionsUntilServerIsDrained(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.SetActive(3, 1, 0)
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for status := range c.MonitorDrain(context.Background(), "test", "10.0.0.1:80") {
+		if status.Err != nil {
+			t.Fatal(status.Err)
+		}
+		got = append(got, status.Active)
+	}
+	want := []uint64{3, 1, 0}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMonitorDrain_DeliversErrorOnUnknownServer(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	status := <-c.MonitorDrain(context.Background(), "test", "10.0.0.2:80")
+	if status.Err == nil {
+		t.Fatal("want error on unknown server, got nil")
+	}
+}
+
+func TestDrainHTTPServer_PutsServerIntoDrainingMode(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.DrainHTTPServer(context.Background(), "test", "10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+	servers, err := c.GetHTTPServers(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || !servers[0].Drain {
+		t.Errorf("want draining server, got %+v", servers)
+	}
+}
+
+func TestDrainHTTPServer_FindsServerByNormalizedAddress(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "backend.example.com:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.DrainHTTPServer(context.Background(), "test", "Backend.Example.com"); err != nil {
+		t.Fatal(err)
+	}
+	servers, err := c.GetHTTPServers(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || !servers[0].Drain {
+		t.Errorf("want draining server, got %+v", servers)
+	}
+}
+
+func TestUndrainHTTPServer_TakesServerOutOfDrainingMode(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80", Drain: true})
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.UndrainHTTPServer(context.Background(), "test", "10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+	servers, err := c.GetHTTPServers(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Drain {
+		t.Errorf("want server not draining, got %+v", servers)
+	}
+}
+
+func TestDrainHTTPServer_FailsWithErrServerNotFoundOnUnknownServer(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.DrainHTTPServer(context.Background(), "test", "10.0.0.2:80")
+	if !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestWaitForDrain_ReturnsWhenServerHasNoActiveConnections(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.SetActive(3, 1, 0)
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.WaitForDrain(context.Background(), "test", "10.0.0.1:80", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForDrain_WaitsForServerGivenWithoutPort(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.SetActive(3, 1, 0)
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.WaitForDrain(context.Background(), "test", "10.0.0.1", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForDrain_FailsWhenContextExpiresBeforeServerIsDrained(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("draining", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.SetActive(5)
+
+	c := newNginxTestClient(ts.URL, t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.WaitForDrain(ctx, "test", "10.0.0.1:80", time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWaitForDrain_FailsOnUnknownServer(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.WaitForDrain(context.Background(), "test", "10.0.0.2:80", time.Millisecond); err == nil {
+		t.Fatal("want error on unknown server, got nil")
+	}
+}
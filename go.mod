@@ -1,6 +1,6 @@
 module github.com/qba73/ngx
 
-go 1.20
+go 1.23
 
 require (
 	github.com/google/go-cmp v0.5.9
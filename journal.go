@@ -0,0 +1,117 @@
+package ngx
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalEntry represents a single change the Client made in NGINX.
+type JournalEntry struct {
+	Time    time.Time       `json:"time"`
+	Actor   string          `json:"actor,omitempty"`
+	URL     string          `json:"url"`
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Journal records changes the Client makes in NGINX, like adding
+// or removing upstream servers and key-value pairs. The dynamic
+// configuration never appears in nginx.conf, so the journal is
+// the place to check who changed it and when.
+type Journal interface {
+	Record(JournalEntry) error
+	Entries() ([]JournalEntry, error)
+}
+
+// FileJournal is a Journal that stores entries in a file,
+// one JSON encoded entry per line.
+type FileJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileJournal takes the path of the journal file and constructs a new
+// file journal. The file is created when the first entry is recorded.
+func NewFileJournal(path string) (*FileJournal, error) {
+	if path == "" {
+		return nil, errors.New("empty journal path")
+	}
+	return &FileJournal{path: path}, nil
+}
+
+// Record appends the entry to the journal file.
+func (j *FileJournal) Record(e JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	if err := json.NewEncoder(f).Encode(e); err != nil {
+		f.Close()
+		return fmt.Errorf("writing journal entry: %w", err)
+	}
+	return f.Close()
+}
+
+// Entries returns all entries recorded in the journal file, oldest first.
+func (j *FileJournal) Entries() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("reading journal entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	return entries, nil
+}
+
+// record records the change in the journal, if the Client has one,
+// and returns the error of the change, if any. A failure to record
+// the change never fails the change, which NGINX has already made or
+// rejected. It's logged at the warning level of the logger configured
+// with WithLogger.
+func (c Client) record(method, path string, payload []byte, err error) error {
+	if c.journal == nil {
+		return err
+	}
+	e := JournalEntry{
+		Time:    c.clock().Now().UTC(),
+		Actor:   c.actor,
+		URL:     c.URL,
+		Method:  method,
+		Path:    path,
+		Payload: payload,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if jerr := c.journal.Record(e); jerr != nil && c.logger != nil {
+		c.logger.Warn("recording change in journal failed", "method", method, "path", path, "error", jerr)
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package ngx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestFileJournal_RecordsChangesMadeByClient(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t)
+
+	journal, err := ngx.NewFileJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ngx.NewClient(ts.URL, ngx.WithJournal(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteHTTPServer(context.Background(), "test", "10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("want 2 journal entries, got %d", len(entries))
+	}
+	if entries[0].Method != http.MethodPost || entries[0].Path != "http/upstreams/test/servers/" {
+		t.Errorf("want POST http/upstreams/test/servers/, got %s %s", entries[0].Method, entries[0].Path)
+	}
+	if string(entries[0].Payload) != `{"server":"10.0.0.1:80"}` {
+		t.Errorf("want server payload, got %s", entries[0].Payload)
+	}
+	if entries[1].Method != http.MethodDelete || entries[1].Path != "http/upstreams/test/servers/1" {
+		t.Errorf("want DELETE http/upstreams/test/servers/1, got %s %s", entries[1].Method, entries[1].Path)
+	}
+	if entries[1].URL != ts.URL {
+		t.Errorf("want journal entry URL %s, got %s", ts.URL, entries[1].URL)
+	}
+}
+
+func TestFileJournal_RecordsActorConfiguredWithActor(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t)
+
+	journal, err := ngx.NewFileJournal(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ngx.NewClient(ts.URL, ngx.WithJournal(journal), ngx.WithActor("deployer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "deployer" {
+		t.Fatalf("want 1 journal entry of deployer, got %+v", entries)
+	}
+}
+
+type failingJournal struct{}
+
+func (failingJournal) Record(ngx.JournalEntry) error {
+	return errors.New("disk full")
+}
+
+func (failingJournal) Entries() ([]ngx.JournalEntry, error) {
+	return nil, nil
+}
+
+func TestUpdateHTTPServers_MakesAllChangesWhenJournalFails(t *testing.T) {
+	t.Parallel()
+	fake, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	c, err := ngx.NewClient(ts.URL, ngx.WithJournal(failingJournal{}), ngx.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}, {Server: "10.0.0.3:80"}}
+	added, deleted, _, err := c.UpdateHTTPServers(context.Background(), "test", servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || len(deleted) != 1 {
+		t.Errorf("want 2 servers added and 1 deleted, got added %v, deleted %v", added, deleted)
+	}
+	want := []string{"10.0.0.2:80", "10.0.0.3:80"}
+	if got := fake.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	if got := strings.Count(buf.String(), "recording change in journal failed"); got != 3 {
+		t.Errorf("want 3 journal failures logged, got %d in %q", got, buf.String())
+	}
+}
+
+func TestClient_ReturnsErrorOfFailedChangeWhenJournalFails(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusBadRequest, "UpstreamBadAddress", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithJournal(failingJournal{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.WithoutExistenceCheck())
+	if !errors.Is(err, ngx.ErrInvalidServer) {
+		t.Fatalf("want ErrInvalidServer, got %v", err)
+	}
+}
@@ -2,13 +2,23 @@ package ngx
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -19,12 +29,34 @@ const (
 	// APIVersion is the default version of NGINX Plus API supported by the client.
 	defaultAPIVersion = 8
 
-	pathNotFoundCode  = "PathNotFound"
-	streamContext     = true
-	httpContext       = false
-	defaultServerPort = "80"
+	pathNotFoundCode     = "PathNotFound"
+	upstreamNotFoundCode = "UpstreamNotFound"
+	streamContext        = true
+	httpContext          = false
+	defaultServerPort    = "80"
+
+	defaultPollInterval = time.Second
+
+	// defaultDetectTimeout limits detection of the API version
+	// when the Client has no timeout configured with WithTimeout.
+	defaultDetectTimeout = 10 * time.Second
+
+	// workersAPIVersion is the first version of NGINX Plus API
+	// that exposes stats of worker processes.
+	workersAPIVersion = 9
+
+	// licenseAPIVersion is the first version of NGINX Plus API
+	// that exposes license information.
+	licenseAPIVersion = 9
+
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
 )
 
+// supportedAPIVersions are the versions of NGINX Plus API
+// supported by the client, in ascending order.
+var supportedAPIVersions = []int{4, 5, 6, 7, 8, 9}
+
 var (
 	// Default values for servers in Upstreams.
 	defaultMaxConns    = 0
@@ -36,6 +68,45 @@ var (
 	defaultWeight      = 1
 )
 
+var (
+	// ErrConfigReloaded is returned when NGINX reloads its configuration
+	// while the Client updates servers of an upstream.
+	ErrConfigReloaded = errors.New("nginx configuration reloaded")
+
+	// ErrUnsupportedAPIVersion is returned when the requested stats
+	// or operation are not available in the version of NGINX API
+	// the Client talks to.
+	ErrUnsupportedAPIVersion = errors.New("unsupported NGINX API version")
+
+	// ErrResponseTooLarge is returned when the response body exceeds
+	// the size configured with WithMaxResponseBytes.
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrServerNotHealthy is returned when a server added to an upstream
+	// doesn't pass health checks in the given time.
+	ErrServerNotHealthy = errors.New("server is not healthy")
+
+	// ErrServerExists is returned when the server being added
+	// to an upstream is already there.
+	ErrServerExists = errors.New("server already exists")
+
+	// ErrServerNotFound is returned when the server being removed
+	// or updated is not in the upstream.
+	ErrServerNotFound = errors.New("server doesn't exist")
+
+	// ErrInvalidServer is returned when NGINX rejects the parameters
+	// of the server being added or updated.
+	ErrInvalidServer = errors.New("invalid server")
+
+	// ErrConcurrentModification is returned when servers of an upstream
+	// change while the Client updates them.
+	ErrConcurrentModification = errors.New("upstream modified concurrently")
+
+	// ErrTooFewServers is returned when an update of an upstream gets
+	// fewer servers than the minimum configured with WithMinServers.
+	ErrTooFewServers = errors.New("too few servers")
+)
+
 // UpstreamServer lets you configure HTTP upstreams.
 type UpstreamServer struct {
 	ID          int    `json:"id,omitempty"`
@@ -87,6 +158,8 @@ type Stats struct {
 	HTTPLimitRequests      HTTPLimitRequests
 	HTTPLimitConnections   HTTPLimitConnections
 	StreamLimitConnections StreamLimitConnections
+	Workers                Workers
+	Extensions             map[string]interface{}
 }
 
 // NginxInfo contains general information about NGINX Plus.
@@ -230,10 +303,26 @@ type HTTPRequests struct {
 }
 
 // SSL represents SSL related stats.
+// NoCommonProtocol, NoCommonCipher, HandshakeTimeout, PeerRejectedCert
+// and VerifyFailures are reported starting from version 8 of NGINX Plus API.
 type SSL struct {
 	Handshakes       uint64
-	HandshakesFailed uint64 `json:"handshakes_failed"`
-	SessionReuses    uint64 `json:"session_reuses"`
+	HandshakesFailed uint64         `json:"handshakes_failed"`
+	SessionReuses    uint64         `json:"session_reuses"`
+	NoCommonProtocol uint64         `json:"no_common_protocol"`
+	NoCommonCipher   uint64         `json:"no_common_cipher"`
+	HandshakeTimeout uint64         `json:"handshake_timeout"`
+	PeerRejectedCert uint64         `json:"peer_rejected_cert"`
+	VerifyFailures   VerifyFailures `json:"verify_failures"`
+}
+
+// VerifyFailures represents SSL certificate verification failures.
+type VerifyFailures struct {
+	NoCert           uint64 `json:"no_cert"`
+	ExpiredCert      uint64 `json:"expired_cert"`
+	RevokedCert      uint64 `json:"revoked_cert"`
+	HostnameMismatch uint64 `json:"hostname_mismatch"`
+	Other            uint64 `json:"other"`
 }
 
 // ServerZones is map of server zone stats by zone name
@@ -370,6 +459,58 @@ type StreamUpstream struct {
 	Zone    string
 }
 
+// Peers returns an iterator over peers of all upstreams. It yields
+// the upstream name and the peer. Upstreams are visited in unspecified order.
+func (u Upstreams) Peers() iter.Seq2[string, Peer] {
+	return func(yield func(string, Peer) bool) {
+		for name, upstream := range u {
+			for _, peer := range upstream.Peers {
+				if !yield(name, peer) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Peers returns an iterator over peers of all stream upstreams. It yields
+// the upstream name and the peer. Upstreams are visited in unspecified order.
+func (u StreamUpstreams) Peers() iter.Seq2[string, StreamPeer] {
+	return func(yield func(string, StreamPeer) bool) {
+		for name, upstream := range u {
+			for _, peer := range upstream.Peers {
+				if !yield(name, peer) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// AllPeers returns an iterator over peers of all HTTP upstreams in stats.
+// It yields the upstream name and the peer.
+func (s Stats) AllPeers() iter.Seq2[string, Peer] {
+	return s.Upstreams.Peers()
+}
+
+// AllStreamPeers returns an iterator over peers of all stream upstreams
+// in stats. It yields the upstream name and the peer.
+func (s Stats) AllStreamPeers() iter.Seq2[string, StreamPeer] {
+	return s.StreamUpstreams.Peers()
+}
+
+// AllServerZones returns an iterator over HTTP server zones in stats.
+// It yields the zone name and the zone stats in unspecified order.
+func (s Stats) AllServerZones() iter.Seq2[string, ServerZone] {
+	return maps.All(s.ServerZones)
+}
+
+// AllStreamServerZones returns an iterator over stream server zones in stats.
+// It yields the zone name and the zone stats in unspecified order.
+func (s Stats) AllStreamServerZones() iter.Seq2[string, StreamServerZone] {
+	return maps.All(s.StreamServerZones)
+}
+
 // Queue represents queue related stats for an upstream.
 type Queue struct {
 	Size      int
@@ -482,6 +623,37 @@ type Processes struct {
 	Respawned int
 }
 
+// Worker represents connections and requests stats of an NGINX worker process.
+type Worker struct {
+	ID          int
+	ProcessID   uint64 `json:"pid"`
+	Connections Connections
+	HTTP        WorkerHTTP
+}
+
+// WorkerHTTP represents HTTP requests stats of a worker process.
+type WorkerHTTP struct {
+	HTTPRequests HTTPRequests `json:"requests"`
+}
+
+// Workers represents stats of NGINX worker processes.
+type Workers []Worker
+
+// License represents license information of NGINX Plus.
+type License struct {
+	ActiveTill time.Time
+	Eval       bool
+	Reporting  LicenseReporting
+}
+
+// LicenseReporting represents the state of usage reporting
+// of the NGINX Plus license.
+type LicenseReporting struct {
+	Healthy bool
+	Fails   uint64
+	Grace   time.Duration
+}
+
 // HTTPLimitRequest represents HTTP Requests Rate Limiting
 type HTTPLimitRequest struct {
 	Passed         uint64
@@ -522,27 +694,348 @@ func WithHTTPClient(h *http.Client) option {
 	}
 }
 
+// WithBaseURL is a func option that configures the base URL
+// of the NGINX API, for example when cloning a Client with Clone.
+func WithBaseURL(baseURL string) option {
+	return func(c *Client) error {
+		if baseURL == "" {
+			return errors.New("empty baseURL string")
+		}
+		c.URL = baseURL
+		return nil
+	}
+}
+
 // WithVersion is a func option that configures version of the NGINX API
 // the Client talks to. It is user's responsibility to provide valid
 // version of the NGINX Plus that the Client talks to.
-// Valid versions are 4,5,6,7,8. The Client's default version is 8.
+// Valid versions are 4,5,6,7,8,9. The Client's default version is 8.
 func WithVersion(v int) option {
 	return func(c *Client) error {
-		switch v {
-		case 4, 5, 6, 7, 8:
-			c.version = v
-			return nil
-		default:
+		if !slices.Contains(supportedAPIVersions, v) {
+			return fmt.Errorf("version %d: %w", v, ErrUnsupportedAPIVersion)
+		}
+		c.version = v
+		c.versionSet = true
+		return nil
+	}
+}
+
+// WithAutoVersion is a func option that configures the Client to detect
+// the version of the NGINX API when it's constructed. NewClient queries
+// the API root for the versions supported by NGINX and picks the highest
+// version supported by both NGINX and the Client. Detection takes at most
+// the timeout configured with WithTimeout, or 10 seconds without it.
+// The option can't be used together with WithVersion.
+func WithAutoVersion() option {
+	return func(c *Client) error {
+		c.autoVersion = true
+		return nil
+	}
+}
+
+// WithSectionTimeout is a func option that configures the maximum time
+// the Client waits for a single stats section in GetStats and GetStatsStream.
+func WithSectionTimeout(d time.Duration) option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("section timeout must be positive")
+		}
+		c.sectionTimeout = d
+		return nil
+	}
+}
+
+// WithTimeout is a func option that configures the maximum time
+// of a single API call. The timeout applies only to calls made with
+// a context without a deadline, so a hung NGINX endpoint can't stall
+// the Client indefinitely.
+func WithTimeout(d time.Duration) option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithPollInterval is a func option that configures how often the Client
+// polls NGINX stats when it waits for a state change, for example
+// for peers to become healthy or to drain. The default interval is 1 second.
+func WithPollInterval(d time.Duration) option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("poll interval must be positive")
+		}
+		c.pollInterval = d
+		return nil
+	}
+}
+
+// WithFallbackURLs is a func option that configures alternative base URLs
+// of the same NGINX instance, for example its IPv4 and IPv6 addresses.
+// When the Client can't connect to its base URL, it sends the request
+// to the fallback URLs, in the given order.
+func WithFallbackURLs(urls ...string) option {
+	return func(c *Client) error {
+		for _, u := range urls {
+			if u == "" {
+				return errors.New("empty fallback URL string")
+			}
+		}
+		c.fallbackURLs = append(c.fallbackURLs, urls...)
+		return nil
+	}
+}
+
+// WithAPIKey is a func option that configures the Client to send
+// the header with the given value in every request, for example
+// "X-Api-Key" or "Authorization" with a bearer token, to authenticate
+// to an auth proxy in front of the NGINX API.
+func WithAPIKey(header, value string) option {
+	return func(c *Client) error {
+		if header == "" {
+			return errors.New("empty API key header name")
+		}
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Set(header, value)
+		return nil
+	}
+}
+
+// WithHeaders is a func option that configures the Client to send
+// the headers in every request, for example tenant IDs required
+// by a proxy in front of the NGINX API.
+func WithHeaders(h http.Header) option {
+	return func(c *Client) error {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		for k, vals := range h {
+			if k == "" {
+				return errors.New("empty header name")
+			}
+			for _, v := range vals {
+				c.headers.Add(k, v)
+			}
+		}
+		return nil
+	}
+}
+
+// WithLogger is a func option that configures the Client to log
+// every request it sends, with its method, URL, response status
+// and duration, at the debug level of the logger.
+func WithLogger(l *slog.Logger) option {
+	return func(c *Client) error {
+		if l == nil {
+			return errors.New("nil logger")
+		}
+		c.logger = l
+		return nil
+	}
+}
+
+// WithDefaultServerPort is a func option that configures the port
+// the Client adds to upstream servers given without a port, when it
+// updates servers of HTTP and stream upstreams. The default port is 80.
+func WithDefaultServerPort(port string) option {
+	return func(c *Client) error {
+		if err := validateDefaultPort(port); err != nil {
+			return err
+		}
+		c.defaultPort = port
+		return nil
+	}
+}
+
+// validateDefaultPort checks that the default server port
+// is a number between 1 and 65535.
+func validateDefaultPort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid default server port %q", port)
+	}
+	return nil
+}
+
+// InstrumentFunc is called after every API call with the name of
+// the operation, like "GET 8/http/upstreams" or "GET /" for the API root,
+// the duration of the call and its error, if any.
+type InstrumentFunc func(op string, d time.Duration, err error)
+
+// WithInstrumentation is a func option that configures the Client
+// to call the function after every API call, so users can feed
+// latency and errors of the calls into their telemetry systems.
+func WithInstrumentation(fn InstrumentFunc) option {
+	return func(c *Client) error {
+		if fn == nil {
+			return errors.New("nil instrumentation func")
+		}
+		c.instrument = fn
+		return nil
+	}
+}
+
+// WithCompression is a func option that configures the Client to request
+// gzip compressed responses and decompress them, which reduces the size
+// of large stats payloads, even when the transport of the HTTP client
+// has compression disabled.
+func WithCompression() option {
+	return func(c *Client) error {
+		c.compression = true
+		return nil
+	}
+}
+
+// WithMaxResponseBytes is a func option that limits the size of response
+// bodies the Client reads, so a misbehaving or wrongly pointed endpoint
+// can't exhaust the memory. Calls with larger responses fail
+// with ErrResponseTooLarge.
+func WithMaxResponseBytes(n int64) option {
+	return func(c *Client) error {
+		if n <= 0 {
+			return errors.New("max response bytes must be positive")
+		}
+		c.maxResponseBytes = n
+		return nil
+	}
+}
+
+// WithStrictDecoding is a func option that configures the Client to fail
+// calls when responses hold fields the Client doesn't model. It helps
+// to find out what a new NGINX release reports that the Client
+// doesn't support yet.
+func WithStrictDecoding() option {
+	return func(c *Client) error {
+		c.strictDecoding = true
+		return nil
+	}
+}
+
+// WithClock is a func option that configures the Client to use
+// the given Clock instead of the system time.
+func WithClock(clk Clock) option {
+	return func(c *Client) error {
+		if clk == nil {
+			return errors.New("nil clock")
+		}
+		c.clk = clk
+		return nil
+	}
+}
+
+// WithActor is a func option that configures the name of who makes
+// changes with the Client, like a user or a tool, recorded as the actor
+// of journal entries.
+func WithActor(name string) option {
+	return func(c *Client) error {
+		if name == "" {
+			return errors.New("empty actor")
+		}
+		c.actor = name
+		return nil
+	}
+}
+
+// WithJournal is a func option that configures the Client
+// to record every change it makes in NGINX in the journal.
+func WithJournal(j Journal) option {
+	return func(c *Client) error {
+		if j == nil {
+			return errors.New("nil journal")
+		}
+		c.journal = j
+		return nil
+	}
+}
+
+type requestConfig struct {
+	fields  []string
+	version int
+}
+
+// apiVersion returns the version of NGINX API configured for the request,
+// or the given default version of the Client.
+func (cfg requestConfig) apiVersion(def int) int {
+	if cfg.version != 0 {
+		return cfg.version
+	}
+	return def
+}
+
+func newRequestConfig(opts ...requestOption) (requestConfig, error) {
+	cfg := requestConfig{}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return requestConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// requestOption helps to configure a single request to the NGINX API.
+type requestOption func(*requestConfig) error
+
+// WithFields is a func option that limits the stats returned by the API
+// to the given fields, for example "requests" and "responses" of server zones,
+// to reduce the size of responses on large deployments. Fields not
+// returned by the API are left with zero values. The option can be passed
+// to methods that return stats, like GetServerZones, GetUpstreams or GetCaches.
+func WithFields(fields ...string) requestOption {
+	return func(cfg *requestConfig) error {
+		for _, f := range fields {
+			if f == "" {
+				return errors.New("empty field name")
+			}
+		}
+		cfg.fields = append(cfg.fields, fields...)
+		return nil
+	}
+}
+
+// WithAPIVersion is a func option that configures a single request
+// to use the given version of NGINX API instead of the version
+// of the Client, for example when one Client talks to a fleet
+// of NGINX instances of different versions.
+func WithAPIVersion(v int) requestOption {
+	return func(cfg *requestConfig) error {
+		if !slices.Contains(supportedAPIVersions, v) {
+			return fmt.Errorf("version %d: %w", v, ErrUnsupportedAPIVersion)
 		}
-		return errors.New("unsupported NGINX API version")
+		cfg.version = v
+		return nil
 	}
 }
 
 // NginxClient lets you access NGINX Plus API.
 type Client struct {
-	version    int
-	URL        string
-	HTTPClient *http.Client
+	version          int
+	sectionTimeout   time.Duration
+	timeout          time.Duration
+	pollInterval     time.Duration
+	journal          Journal
+	actor            string
+	fallbackURLs     []string
+	clk              Clock
+	tlsConfig        *tls.Config
+	certReloader     *certReloader
+	clientCert       *tls.Certificate
+	tlsChanged       bool
+	autoVersion      bool
+	versionSet       bool
+	headers          http.Header
+	logger           *slog.Logger
+	defaultPort      string
+	instrument       InstrumentFunc
+	compression      bool
+	maxResponseBytes int64
+	strictDecoding   bool
+	URL              string
+	HTTPClient       *http.Client
 }
 
 // NewClient takes NGINX base URL and constructs a new default client.
@@ -553,16 +1046,116 @@ func NewClient(baseURL string, opts ...option) (*Client, error) {
 		return nil, errors.New("empty baseURL string")
 	}
 	c := Client{
-		version:    defaultAPIVersion,
-		URL:        baseURL,
-		HTTPClient: http.DefaultClient,
+		version:      defaultAPIVersion,
+		pollInterval: defaultPollInterval,
+		URL:          baseURL,
+		HTTPClient:   http.DefaultClient,
+	}
+	if err := c.apply(opts...); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Clone returns a copy of the Client configured with the options,
+// for example with a different base URL or API version. The copy
+// shares the HTTP client, including its TLS setup, with the Client,
+// so fleet tools can cheaply create clients of many NGINX instances.
+// When the options change the TLS setup, the copy gets its own HTTP
+// client, which keeps the client certificate unless it's replaced.
+func (c Client) Clone(opts ...option) (*Client, error) {
+	clone := c
+	clone.fallbackURLs = slices.Clone(c.fallbackURLs)
+	clone.headers = c.headers.Clone()
+	clone.autoVersion = false
+	clone.versionSet = false
+	if err := clone.apply(opts...); err != nil {
+		return nil, err
 	}
+	return &clone, nil
+}
+
+// apply configures the Client with the options and applies the settings
+// that depend on all options, like TLS and the detected API version.
+func (c *Client) apply(opts ...option) error {
 	for _, opt := range opts {
-		if err := opt(&c); err != nil {
-			return nil, err
+		if err := opt(c); err != nil {
+			return err
 		}
 	}
-	return &c, nil
+	if err := c.configureTLS(); err != nil {
+		return err
+	}
+	if c.autoVersion {
+		if c.versionSet {
+			return errors.New("ngx: WithVersion and WithAutoVersion can't be used together")
+		}
+		timeout := c.timeout
+		if timeout == 0 {
+			timeout = defaultDetectTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		v, err := c.detectVersion(ctx)
+		if err != nil {
+			return err
+		}
+		c.version = v
+	}
+	return nil
+}
+
+// ListSupportedAPIVersions returns the versions of NGINX API
+// supported by the NGINX instance, as listed by the API root.
+func (c Client) ListSupportedAPIVersions(ctx context.Context) ([]int, error) {
+	var versions []int
+	if err := c.getPath(ctx, "", &versions); err != nil {
+		return nil, fmt.Errorf("ngx: listing supported API versions: %w", err)
+	}
+	return versions, nil
+}
+
+// ListEndpoints returns the first-level endpoints available in
+// the configured version of NGINX API, for example "nginx", "http"
+// or "stream". Callers can use it to detect which parts of the API,
+// like stream or key-value support, NGINX has configured.
+func (c Client) ListEndpoints(ctx context.Context) ([]string, error) {
+	var endpoints []string
+	if err := c.get(ctx, "", &endpoints); err != nil {
+		return nil, fmt.Errorf("ngx: listing endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// requireVersion returns ErrUnsupportedAPIVersion when the version
+// of NGINX API the request is sent to is older than the given version.
+// The request version is the version of the Client, unless the request
+// options override it with WithAPIVersion.
+func (c Client) requireVersion(v int, opts ...requestOption) error {
+	cfg, err := newRequestConfig(opts...)
+	if err != nil {
+		return err
+	}
+	version := cfg.apiVersion(c.version)
+	if version < v {
+		return fmt.Errorf("version %d, requires %d: %w", version, v, ErrUnsupportedAPIVersion)
+	}
+	return nil
+}
+
+// detectVersion returns the highest version of NGINX API
+// supported by both NGINX and the Client.
+func (c Client) detectVersion(ctx context.Context) (int, error) {
+	versions, err := c.ListSupportedAPIVersions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ngx: detecting API version: %w", err)
+	}
+	for i := len(supportedAPIVersions) - 1; i >= 0; i-- {
+		if slices.Contains(versions, supportedAPIVersions[i]) {
+			return supportedAPIVersions[i], nil
+		}
+	}
+	return 0, fmt.Errorf("ngx: detecting API version: no supported version in %v", versions)
 }
 
 // GetNginxInfo returns status of nginx running instance.
@@ -616,6 +1209,27 @@ func (c Client) GetNGINXStatus(ctx context.Context, fields ...string) (NginxInfo
 	return info, nil
 }
 
+// WaitForAPI waits until NGINX is up and its API answers requests
+// for NGINX info. It retries the requests with exponential backoff
+// and returns the last error if the API doesn't answer in the given time.
+func (c Client) WaitForAPI(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	backoff := initialBackoff
+	for {
+		_, err := c.GetNginxInfo(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for NGINX API: %w", err)
+		case <-c.clock().After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
 // CheckIfUpstreamExists checks if the upstream exists in NGINX.
 // If the upstream doesn't exist, it returns the error.
 func (c Client) CheckIfUpstreamExists(ctx context.Context, upstream string) error {
@@ -635,80 +1249,635 @@ func (c Client) GetHTTPServers(ctx context.Context, upstream string) ([]Upstream
 	return servers, nil
 }
 
-// AddHTTPServer adds the server to the upstream.
-func (c Client) AddHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
-	id, err := c.getIDOfHTTPServer(ctx, upstream, server.Server)
+// GetHTTPServerByID returns the server of the upstream with the given ID.
+func (c Client) GetHTTPServerByID(ctx context.Context, upstream string, id int) (UpstreamServer, error) {
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, id)
+	var server UpstreamServer
+	if err := c.get(ctx, path, &server); err != nil {
+		return UpstreamServer{}, fmt.Errorf("retrieving HTTP server %v of upstream %v: %w", id, upstream, err)
+	}
+	return server, nil
+}
+
+// GetHTTPServerByName returns the server of the upstream with the given address,
+// including its ID and current parameters. Addresses are compared
+// the same way as in DetermineServerUpdates. If the upstream doesn't
+// have the server, it returns ErrServerNotFound.
+func (c Client) GetHTTPServerByName(ctx context.Context, upstream, server string) (UpstreamServer, error) {
+	servers, err := c.GetHTTPServers(ctx, upstream)
+	if err != nil {
+		return UpstreamServer{}, fmt.Errorf("retrieving HTTP server %v of upstream %v: %w", server, upstream, err)
+	}
+	address := normalizeServer(server, c.serverPort())
+	for _, s := range servers {
+		if normalizeServer(s.Server, c.serverPort()) == address {
+			return s, nil
+		}
+	}
+	return UpstreamServer{}, fmt.Errorf("retrieving HTTP server %v of upstream %v: %w", server, upstream, ErrServerNotFound)
+}
+
+// AddHTTPServer adds the server to the upstream. Before adding the server
+// it checks that the server isn't already in the upstream, unless
+// the check is skipped with WithoutExistenceCheck.
+func (c Client) AddHTTPServer(ctx context.Context, upstream string, server UpstreamServer, opts ...addOption) error {
+	cfg, err := newAddConfig(opts...)
 	if err != nil {
 		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, err)
 	}
-	if id != -1 {
-		return fmt.Errorf("adding %v server to %v upstream: server already exists", server.Server, upstream)
+	if !cfg.skipExistenceCheck {
+		id, err := c.getIDOfHTTPServer(ctx, upstream, server.Server)
+		if err != nil {
+			return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, err)
+		}
+		if id != -1 {
+			return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, ErrServerExists)
+		}
 	}
 	path := fmt.Sprintf("http/upstreams/%v/servers/", upstream)
-	if err = c.post(ctx, path, server); err != nil {
-		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, err)
+	if err := c.post(ctx, path, server); err != nil {
+		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, serverError(err))
 	}
 	return nil
 }
 
-// DeleteHTTPServer the server from the upstream.
-func (c Client) DeleteHTTPServer(ctx context.Context, upstream string, server string) error {
-	id, err := c.getIDOfHTTPServer(ctx, upstream, server)
+// AddResult represents the result of adding a server to an upstream
+// with AddHTTPServers or AddStreamServers. Added is false for servers
+// that were already in the upstream, and for servers that failed
+// to be added with Err.
+type AddResult struct {
+	Server string
+	Added  bool
+	Err    error
+}
+
+// AddHTTPServers adds the servers that are not yet in the upstream.
+// It reads servers of the upstream once, so adding n servers costs
+// n+1 requests. It attempts to add all missing servers, changing up to
+// the number of servers configured with WithParallelism at once, which is
+// the only supported option. It returns the results in the order
+// of the servers, and an error joining the Err of all failed results.
+// Servers are compared by normalized address, as in DetermineServerUpdates.
+func (c Client) AddHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...updateOption) ([]AddResult, error) {
+	cfg, err := newBulkAddConfig(opts...)
 	if err != nil {
-		return fmt.Errorf("removing %v server from  %v upstream: %w", server, upstream, err)
+		return nil, fmt.Errorf("adding servers to %v upstream: %w", upstream, err)
 	}
-	if id == -1 {
-		return fmt.Errorf("removing %v server from %v upstream: server doesn't exist", server, upstream)
+	serversInNginx, err := c.GetHTTPServers(ctx, upstream)
+	if err != nil {
+		return nil, fmt.Errorf("adding servers to %v upstream: %w", upstream, err)
+	}
+	existing := make(map[string]bool, len(serversInNginx))
+	for _, server := range serversInNginx {
+		existing[normalizeServer(server.Server, c.serverPort())] = true
+	}
+	results := make([]AddResult, len(servers))
+	var toAdd []int
+	for i, server := range servers {
+		results[i].Server = server.Server
+		key := normalizeServer(server.Server, c.serverPort())
+		if existing[key] {
+			continue
+		}
+		existing[key] = true
+		toAdd = append(toAdd, i)
 	}
-	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, id)
-	if err = c.delete(ctx, path, http.StatusOK); err != nil {
-		return fmt.Errorf("removing %v server from %v upstream: %w", server, upstream, err)
+	runAll(toAdd, cfg.parallelism, func(i int) {
+		results[i].Err = c.AddHTTPServer(ctx, upstream, servers[i], WithoutExistenceCheck())
+		results[i].Added = results[i].Err == nil
+	})
+	if err := joinAddErrors(results); err != nil {
+		return results, fmt.Errorf("adding servers to %v upstream: %w", upstream, err)
 	}
-	return nil
+	return results, nil
 }
 
-// UpdateHTTPServers updates the servers of the upstream.
-// Servers that are in the slice, but don't exist in NGINX will be added to NGINX.
-// Servers that aren't in the slice, but exist in NGINX, will be removed from NGINX.
-// Servers that are in the slice and exist in NGINX, but have different parameters, will be updated.
-func (c Client) UpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer) ([]UpstreamServer, []UpstreamServer, []UpstreamServer, error) {
-	serversInNginx, err := c.GetHTTPServers(ctx, upstream)
+// newBulkAddConfig returns the config of bulk adds of servers,
+// which only support the WithParallelism option.
+func newBulkAddConfig(opts ...updateOption) (updateConfig, error) {
+	cfg, err := newUpdateConfig(opts...)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
+		return updateConfig{}, err
 	}
-	// We assume port 80 if no port is set for servers.
-	var formattedServers []UpstreamServer
-	for _, server := range servers {
-		server.Server = addPortToServer(server.Server)
-		formattedServers = append(formattedServers, server)
+	if cfg != (updateConfig{parallelism: cfg.parallelism}) {
+		return updateConfig{}, errors.New("adding servers only supports the WithParallelism option")
 	}
+	return cfg, nil
+}
 
-	toAdd, toDelete, toUpdate := determineServerUpdates(formattedServers, serversInNginx)
-
-	for _, server := range toAdd {
-		err := c.AddHTTPServer(ctx, upstream, server)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
+func joinAddErrors(results []AddResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	for _, server := range toDelete {
-		err := c.DeleteHTTPServer(ctx, upstream, server.Server)
+// AddHTTPServerAndWait adds the server to the upstream marked as down,
+// so NGINX doesn't send it requests, and waits until the server passes
+// the last health check, as reported by the upstream peer stats. Then it
+// marks the server as up, unless the server is given as down. It returns
+// ErrServerNotHealthy and leaves the server down if the server doesn't
+// pass health checks in the given time. The upstream must have health
+// checks configured.
+func (c Client) AddHTTPServerAndWait(ctx context.Context, upstream string, server UpstreamServer, timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.New("health timeout must be positive")
+	}
+	down := server.Down != nil && *server.Down
+	addedDown := true
+	server.Down = &addedDown
+	if err := c.AddHTTPServer(ctx, upstream, server); err != nil {
+		return err
+	}
+	if err := c.waitForServer(ctx, upstream, server.Server, timeout, httpContext, passedHealthCheck); err != nil {
+		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, err)
+	}
+	if down {
+		return nil
+	}
+	added, err := c.GetHTTPServerByName(ctx, upstream, server.Server)
+	if err != nil {
+		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, err)
+	}
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, added.ID)
+	if err := c.patch(ctx, path, map[string]bool{"down": false}, http.StatusOK); err != nil {
+		return fmt.Errorf("adding %v server to %v upstream: %w", server.Server, upstream, serverError(err))
+	}
+	return nil
+}
+
+// waitForHealthyServer polls upstream stats until all peers of the server
+// are up and passed the last health check.
+func (c Client) waitForHealthyServer(ctx context.Context, upstream, server string, timeout time.Duration, stream bool) error {
+	return c.waitForServer(ctx, upstream, server, timeout, stream, isPeerHealthy)
+}
+
+// waitForServer polls upstream stats until all peers of the server are ready.
+func (c Client) waitForServer(ctx context.Context, upstream, server string, timeout time.Duration, stream bool, ready func(Peer) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := c.clock().NewTicker(c.interval())
+	defer ticker.Stop()
+	address := addPort(server, c.serverPort())
+	for {
+		peers, err := c.upstreamPeers(ctx, upstream, stream)
+		if err == nil && isServerReady(peers, ready, server, address) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("waiting for %v server: %w", server, err)
+			}
+			return fmt.Errorf("waiting for %v server: %w", server, ErrServerNotHealthy)
+		case <-ticker.C():
+		}
+	}
+}
+
+// upstreamPeers returns peers of the HTTP or stream upstream, fetching
+// stats of only that upstream. Peers of stream upstreams only have
+// the fields shared with HTTP peers set.
+func (c Client) upstreamPeers(ctx context.Context, upstream string, stream bool) ([]Peer, error) {
+	if !stream {
+		u, err := c.GetUpstream(ctx, upstream)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
+			return nil, err
+		}
+		return u.Peers, nil
+	}
+	u, err := c.GetStreamUpstream(ctx, upstream)
+	if err != nil {
+		return nil, err
+	}
+	var peers []Peer
+	for _, p := range u.Peers {
+		peers = append(peers, Peer{
+			ID:           p.ID,
+			Server:       p.Server,
+			Name:         p.Name,
+			State:        p.State,
+			HealthChecks: p.HealthChecks,
+		})
+	}
+	return peers, nil
+}
+
+func isServerReady(peers []Peer, ready func(Peer) bool, names ...string) bool {
+	var found bool
+	for _, p := range peers {
+		if !slices.Contains(names, p.Server) && !slices.Contains(names, p.Name) {
+			continue
+		}
+		if !ready(p) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+func isPeerHealthy(p Peer) bool {
+	return p.State == "up" && p.HealthChecks.LastPassed
+}
+
+// passedHealthCheck reports whether the peer passed the last health check,
+// also when it's marked as down.
+func passedHealthCheck(p Peer) bool {
+	return p.HealthChecks.LastPassed
+}
+
+// DeleteHTTPServer the server from the upstream.
+func (c Client) DeleteHTTPServer(ctx context.Context, upstream string, server string) error {
+	id, err := c.getIDOfHTTPServer(ctx, upstream, server)
+	if err != nil {
+		return fmt.Errorf("removing %v server from  %v upstream: %w", server, upstream, err)
+	}
+	if id == -1 {
+		return fmt.Errorf("removing %v server from %v upstream: %w", server, upstream, ErrServerNotFound)
+	}
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, id)
+	if err = c.delete(ctx, path, http.StatusOK); err != nil {
+		return fmt.Errorf("removing %v server from %v upstream: %w", server, upstream, serverError(err))
+	}
+	return nil
+}
+
+// DeleteHTTPServerByID removes the server with the given ID from the upstream.
+// Unlike DeleteHTTPServer, it doesn't read servers of the upstream to find the ID.
+func (c Client) DeleteHTTPServerByID(ctx context.Context, upstream string, id int) error {
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, id)
+	if err := c.delete(ctx, path, http.StatusOK); err != nil {
+		return fmt.Errorf("removing server %v from %v upstream: %w", id, upstream, serverError(err))
+	}
+	return nil
+}
+
+type updateConfig struct {
+	checkReload     bool
+	reloadRetries   int
+	checkConcurrent bool
+
+	minServers int
+
+	zeroDowntime  bool
+	healthTimeout time.Duration
+
+	dryRun bool
+
+	parallelism int
+}
+
+// updateOption helps to configure how the Client updates servers of an upstream.
+type updateOption func(*updateConfig) error
+
+func newUpdateConfig(opts ...updateOption) (updateConfig, error) {
+	var cfg updateConfig
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return updateConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// WithReloadCheck is a func option that configures the update to compare
+// the NGINX configuration generation before and after applying changes.
+// If NGINX reloaded the configuration in the meantime, which removes
+// dynamically added servers from upstreams without a state file,
+// the update fails with ErrConfigReloaded. The update is run again
+// up to the given number of retries before it fails.
+func WithReloadCheck(retries int) updateOption {
+	return func(cfg *updateConfig) error {
+		if retries < 0 {
+			return errors.New("negative number of retries")
+		}
+		cfg.checkReload = true
+		cfg.reloadRetries = retries
+		return nil
+	}
+}
+
+// WithConcurrencyCheck is a func option that configures the update to read
+// servers of the upstream again right before applying changes. If the servers
+// are different than the servers the changes were computed from, because
+// another client changed the upstream in the meantime, the update fails
+// with ErrConcurrentModification without making any changes.
+func WithConcurrencyCheck() updateOption {
+	return func(cfg *updateConfig) error {
+		cfg.checkConcurrent = true
+		return nil
+	}
+}
+
+// WithMinServers is a func option that configures the update to fail
+// with ErrTooFewServers, without making any changes, when fewer than n
+// servers are passed. It guards against accidentally removing all servers
+// of the upstream, for example when service discovery returns no servers.
+func WithMinServers(n int) updateOption {
+	return func(cfg *updateConfig) error {
+		if n <= 0 {
+			return errors.New("min servers must be positive")
+		}
+		cfg.minServers = n
+		return nil
+	}
+}
+
+// checkMinServers returns ErrTooFewServers when the number of servers
+// is lower than the minimum configured with WithMinServers.
+func (cfg updateConfig) checkMinServers(n int) error {
+	if n < cfg.minServers {
+		return fmt.Errorf("got %d servers, want at least %d: %w", n, cfg.minServers, ErrTooFewServers)
+	}
+	return nil
+}
+
+// WithZeroDowntime is a func option that configures the update to add
+// new servers first, then update changed servers and delete old servers
+// last, so the upstream doesn't run out of servers during the update.
+// If the timeout is positive, the update waits up to the timeout for added
+// servers to pass health checks before it changes other servers, and fails
+// with ErrServerNotHealthy without deleting old servers otherwise.
+// Waiting requires health checks configured for the upstream.
+func WithZeroDowntime(healthTimeout time.Duration) updateOption {
+	return func(cfg *updateConfig) error {
+		if healthTimeout < 0 {
+			return errors.New("negative health timeout")
+		}
+		cfg.zeroDowntime = true
+		cfg.healthTimeout = healthTimeout
+		return nil
+	}
+}
+
+// waitForAddedServers waits until all added servers pass health checks,
+// if configured with WithZeroDowntime.
+func (c Client) waitForAddedServers(ctx context.Context, upstream string, servers []string, cfg updateConfig, stream bool) error {
+	if cfg.healthTimeout == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.healthTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := c.waitForHealthyServer(ctx, upstream, server, cfg.healthTimeout, stream); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// WithDryRun is a func option that configures the update to only compute
+// and return servers to add, delete and update, without changing NGINX.
+// It lets you preview what the update would do. Use PlanHTTPServers
+// or PlanStreamServers to get changes that can be reviewed and applied later.
+func WithDryRun() updateOption {
+	return func(cfg *updateConfig) error {
+		cfg.dryRun = true
+		return nil
+	}
+}
+
+// WithParallelism is a func option that configures the update to change
+// up to n servers at once. By default servers are changed one by one
+// and the update stops at the first failed change. With parallelism
+// greater than one, the update makes all changes of a kind, for example
+// all adds, and returns the joined errors of the failed ones.
+func WithParallelism(n int) updateOption {
+	return func(cfg *updateConfig) error {
+		if n <= 0 {
+			return errors.New("parallelism must be positive")
+		}
+		cfg.parallelism = n
+		return nil
+	}
+}
+
+// forEach calls fn for every item, running up to n calls at once.
+// With n of one or less it calls fn for items in order and returns
+// the first error. Otherwise it returns the joined errors of all calls.
+func forEach[T any](items []T, n int, fn func(T) error) error {
+	if n <= 1 {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make([]error, len(items))
+	runAll(makeRange(len(items)), n, func(i int) {
+		errs[i] = fn(items[i])
+	})
+	return errors.Join(errs...)
+}
+
+// runAll calls fn for every item, running up to n calls at once.
+// With n of one or less it calls fn for items in order.
+func runAll[T any](items []T, n int, fn func(T)) {
+	if n <= 1 {
+		for _, item := range items {
+			fn(item)
+		}
+		return
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// makeRange returns the integers from 0 to n-1.
+func makeRange(n int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = i
+	}
+	return r
+}
+
+type addConfig struct {
+	skipExistenceCheck bool
+}
+
+// addOption helps to configure how the Client adds a server to an upstream.
+type addOption func(*addConfig) error
+
+func newAddConfig(opts ...addOption) (addConfig, error) {
+	var cfg addConfig
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return addConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// WithoutExistenceCheck is a func option that configures adding a server
+// to skip reading servers of the upstream to check that the server
+// isn't there yet. NGINX rejects duplicate servers itself, and the add
+// fails with ErrServerExists, so the option saves a request per server
+// during bulk changes.
+func WithoutExistenceCheck() addOption {
+	return func(cfg *addConfig) error {
+		cfg.skipExistenceCheck = true
+		return nil
+	}
+}
+
+// checkGeneration returns ErrConfigReloaded when the generation of NGINX
+// configuration is different than the given generation.
+func (c Client) checkGeneration(ctx context.Context, generation int) error {
+	info, err := c.GetNginxInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Generation != generation {
+		return fmt.Errorf("generation changed from %d to %d: %w", generation, info.Generation, ErrConfigReloaded)
+	}
+	return nil
+}
+
+// checkUnchanged returns ErrConcurrentModification when the servers
+// of an upstream are different than the servers of the snapshot.
+func checkUnchanged[S ~[]E, E any](snapshot, current S) error {
+	if !cmp.Equal(snapshot, current) {
+		return fmt.Errorf("servers changed after they were read: %w", ErrConcurrentModification)
+	}
+	return nil
+}
 
-	for _, server := range toUpdate {
-		err := c.UpdateHTTPServer(ctx, upstream, server)
+// UpdateHTTPServers updates the servers of the upstream.
+// Servers that are in the slice, but don't exist in NGINX will be added to NGINX.
+// Servers that aren't in the slice, but exist in NGINX, will be removed from NGINX.
+// Servers that are in the slice and exist in NGINX, but have different parameters, will be updated.
+// The update can be customized by passing functional options.
+func (c Client) UpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...updateOption) ([]UpstreamServer, []UpstreamServer, []UpstreamServer, error) {
+	cfg, err := newUpdateConfig(opts...)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
+	}
+	if err := cfg.checkMinServers(len(servers)); err != nil {
+		return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
+	}
+	for attempt := 0; ; attempt++ {
+		toAdd, toDelete, toUpdate, err := c.updateHTTPServers(ctx, upstream, servers, cfg)
+		if errors.Is(err, ErrConfigReloaded) && attempt < cfg.reloadRetries {
+			continue
+		}
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("updating servers of %v upstream: %w", upstream, err)
 		}
+		return toAdd, toDelete, toUpdate, nil
+	}
+}
+
+func (c Client) updateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, cfg updateConfig) ([]UpstreamServer, []UpstreamServer, []UpstreamServer, error) {
+	var generation int
+	if cfg.checkReload {
+		info, err := c.GetNginxInfo(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		generation = info.Generation
 	}
 
+	serversInNginx, err := c.GetHTTPServers(ctx, upstream)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	toAdd, toDelete, toUpdate, err := DetermineServerUpdates(servers, serversInNginx, WithDefaultPort(c.serverPort()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cfg.dryRun {
+		return toAdd, toDelete, toUpdate, nil
+	}
+	if cfg.checkConcurrent {
+		current, err := c.GetHTTPServers(ctx, upstream)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := checkUnchanged(serversInNginx, current); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if err := c.applyHTTPServers(ctx, upstream, toAdd, toDelete, toUpdate, cfg); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if cfg.checkReload {
+		if err := c.checkGeneration(ctx, generation); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 	return toAdd, toDelete, toUpdate, nil
 }
 
+// applyHTTPServers adds, deletes and updates the servers of the upstream.
+// Servers of each kind of change are changed by up to the number
+// of requests configured with WithParallelism at once.
+func (c Client) applyHTTPServers(ctx context.Context, upstream string, toAdd, toDelete, toUpdate []UpstreamServer, cfg updateConfig) error {
+	if cfg.zeroDowntime {
+		return c.applyHTTPServersWithoutDowntime(ctx, upstream, toAdd, toDelete, toUpdate, cfg)
+	}
+	addServer := func(server UpstreamServer) error {
+		return c.AddHTTPServer(ctx, upstream, server, WithoutExistenceCheck())
+	}
+	if err := forEach(toAdd, cfg.parallelism, addServer); err != nil {
+		return err
+	}
+	deleteServer := func(server UpstreamServer) error {
+		return c.DeleteHTTPServerByID(ctx, upstream, server.ID)
+	}
+	if err := forEach(toDelete, cfg.parallelism, deleteServer); err != nil {
+		return err
+	}
+	updateServer := func(server UpstreamServer) error {
+		return c.UpdateHTTPServer(ctx, upstream, server)
+	}
+	return forEach(toUpdate, cfg.parallelism, updateServer)
+}
+
+// applyHTTPServersWithoutDowntime adds, updates and deletes the servers
+// of the upstream in the order configured with WithZeroDowntime.
+func (c Client) applyHTTPServersWithoutDowntime(ctx context.Context, upstream string, toAdd, toDelete, toUpdate []UpstreamServer, cfg updateConfig) error {
+	addServer := func(server UpstreamServer) error {
+		return c.AddHTTPServer(ctx, upstream, server, WithoutExistenceCheck())
+	}
+	if err := forEach(toAdd, cfg.parallelism, addServer); err != nil {
+		return err
+	}
+	var added []string
+	for _, server := range toAdd {
+		added = append(added, server.Server)
+	}
+	if err := c.waitForAddedServers(ctx, upstream, added, cfg, httpContext); err != nil {
+		return err
+	}
+	updateServer := func(server UpstreamServer) error {
+		return c.UpdateHTTPServer(ctx, upstream, server)
+	}
+	if err := forEach(toUpdate, cfg.parallelism, updateServer); err != nil {
+		return err
+	}
+	deleteServer := func(server UpstreamServer) error {
+		return c.DeleteHTTPServerByID(ctx, upstream, server.ID)
+	}
+	return forEach(toDelete, cfg.parallelism, deleteServer)
+}
+
 func (c Client) getIDOfHTTPServer(ctx context.Context, upstream string, name string) (int, error) {
 	servers, err := c.GetHTTPServers(ctx, upstream)
 	if err != nil {
@@ -742,20 +1911,91 @@ func (c Client) GetStreamServers(ctx context.Context, upstream string) ([]Stream
 	return servers, nil
 }
 
-// AddStreamServer adds the stream server to the upstream.
-func (c Client) AddStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error {
-	id, err := c.getIDOfStreamServer(ctx, upstream, server.Server)
+// GetStreamServerByID returns the server of the stream upstream with the given ID.
+func (c Client) GetStreamServerByID(ctx context.Context, upstream string, id int) (StreamUpstreamServer, error) {
+	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, id)
+	var server StreamUpstreamServer
+	if err := c.get(ctx, path, &server); err != nil {
+		return StreamUpstreamServer{}, fmt.Errorf("retrieving stream server %v of upstream %v: %w", id, upstream, err)
+	}
+	return server, nil
+}
+
+// GetStreamServerByName returns the server of the upstream with the given address,
+// including its ID and current parameters. Addresses are compared
+// the same way as in DetermineServerUpdates. If the upstream doesn't
+// have the server, it returns ErrServerNotFound.
+func (c Client) GetStreamServerByName(ctx context.Context, upstream, server string) (StreamUpstreamServer, error) {
+	servers, err := c.GetStreamServers(ctx, upstream)
 	if err != nil {
-		return fmt.Errorf("adding %v stream server to %v upstream: %w", server.Server, upstream, err)
+		return StreamUpstreamServer{}, fmt.Errorf("retrieving stream server %v of upstream %v: %w", server, upstream, err)
 	}
-	if id != -1 {
-		return fmt.Errorf("adding %v stream server to %v upstream: server already exists", server.Server, upstream)
+	address := normalizeServer(server, c.serverPort())
+	for _, s := range servers {
+		if normalizeServer(s.Server, c.serverPort()) == address {
+			return s, nil
+		}
 	}
-	path := fmt.Sprintf("stream/upstreams/%v/servers/", upstream)
-	err = c.post(ctx, path, &server)
+	return StreamUpstreamServer{}, fmt.Errorf("retrieving stream server %v of upstream %v: %w", server, upstream, ErrServerNotFound)
+}
+
+// AddStreamServers adds the stream servers that are not yet
+// in the upstream. It works like AddHTTPServers.
+func (c Client) AddStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer, opts ...updateOption) ([]AddResult, error) {
+	cfg, err := newBulkAddConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("adding stream servers to %v upstream: %w", upstream, err)
+	}
+	serversInNginx, err := c.GetStreamServers(ctx, upstream)
+	if err != nil {
+		return nil, fmt.Errorf("adding stream servers to %v upstream: %w", upstream, err)
+	}
+	existing := make(map[string]bool, len(serversInNginx))
+	for _, server := range serversInNginx {
+		existing[normalizeServer(server.Server, c.serverPort())] = true
+	}
+	results := make([]AddResult, len(servers))
+	var toAdd []int
+	for i, server := range servers {
+		results[i].Server = server.Server
+		key := normalizeServer(server.Server, c.serverPort())
+		if existing[key] {
+			continue
+		}
+		existing[key] = true
+		toAdd = append(toAdd, i)
+	}
+	runAll(toAdd, cfg.parallelism, func(i int) {
+		results[i].Err = c.AddStreamServer(ctx, upstream, servers[i], WithoutExistenceCheck())
+		results[i].Added = results[i].Err == nil
+	})
+	if err := joinAddErrors(results); err != nil {
+		return results, fmt.Errorf("adding stream servers to %v upstream: %w", upstream, err)
+	}
+	return results, nil
+}
+
+// AddStreamServer adds the stream server to the upstream. Before adding
+// the server it checks that the server isn't already in the upstream,
+// unless the check is skipped with WithoutExistenceCheck.
+func (c Client) AddStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer, opts ...addOption) error {
+	cfg, err := newAddConfig(opts...)
 	if err != nil {
 		return fmt.Errorf("adding %v stream server to %v upstream: %w", server.Server, upstream, err)
 	}
+	if !cfg.skipExistenceCheck {
+		id, err := c.getIDOfStreamServer(ctx, upstream, server.Server)
+		if err != nil {
+			return fmt.Errorf("adding %v stream server to %v upstream: %w", server.Server, upstream, err)
+		}
+		if id != -1 {
+			return fmt.Errorf("adding %v stream server to %v upstream: %w", server.Server, upstream, ErrServerExists)
+		}
+	}
+	path := fmt.Sprintf("stream/upstreams/%v/servers/", upstream)
+	if err := c.post(ctx, path, &server); err != nil {
+		return fmt.Errorf("adding %v stream server to %v upstream: %w", server.Server, upstream, serverError(err))
+	}
 	return nil
 }
 
@@ -766,12 +2006,22 @@ func (c Client) DeleteStreamServer(ctx context.Context, upstream string, server
 		return fmt.Errorf("removing %v stream server from  %v upstream: %w", server, upstream, err)
 	}
 	if id == -1 {
-		return fmt.Errorf("removing %v stream server from %v upstream: server doesn't exist", server, upstream)
+		return fmt.Errorf("removing %v stream server from %v upstream: %w", server, upstream, ErrServerNotFound)
 	}
 	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, id)
 	err = c.delete(ctx, path, http.StatusOK)
 	if err != nil {
-		return fmt.Errorf("removing %v stream server from %v upstream: %w", server, upstream, err)
+		return fmt.Errorf("removing %v stream server from %v upstream: %w", server, upstream, serverError(err))
+	}
+	return nil
+}
+
+// DeleteStreamServerByID removes the server with the given ID from the upstream.
+// Unlike DeleteStreamServer, it doesn't read servers of the upstream to find the ID.
+func (c Client) DeleteStreamServerByID(ctx context.Context, upstream string, id int) error {
+	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, id)
+	if err := c.delete(ctx, path, http.StatusOK); err != nil {
+		return fmt.Errorf("removing stream server %v from %v upstream: %w", id, upstream, serverError(err))
 	}
 	return nil
 }
@@ -780,44 +2030,123 @@ func (c Client) DeleteStreamServer(ctx context.Context, upstream string, server
 // Servers that are in the slice, but don't exist in NGINX will be added to NGINX.
 // Servers that aren't in the slice, but exist in NGINX, will be removed from NGINX.
 // Servers that are in the slice and exist in NGINX, but have different parameters, will be updated.
-func (c Client) UpdateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer) ([]StreamUpstreamServer, []StreamUpstreamServer, []StreamUpstreamServer, error) {
-	serversInNginx, err := c.GetStreamServers(ctx, upstream)
+// The update can be customized by passing functional options.
+func (c Client) UpdateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer, opts ...updateOption) ([]StreamUpstreamServer, []StreamUpstreamServer, []StreamUpstreamServer, error) {
+	cfg, err := newUpdateConfig(opts...)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("updating stream servers of %v upstream: %w", upstream, err)
 	}
-
-	var formattedServers []StreamUpstreamServer
-	for _, server := range servers {
-		server.Server = addPortToServer(server.Server)
-		formattedServers = append(formattedServers, server)
+	if err := cfg.checkMinServers(len(servers)); err != nil {
+		return nil, nil, nil, fmt.Errorf("updating stream servers of %v upstream: %w", upstream, err)
 	}
-
-	toAdd, toDelete, toUpdate := determineStreamUpdates(formattedServers, serversInNginx)
-
-	for _, server := range toAdd {
-		err := c.AddStreamServer(ctx, upstream, server)
+	for attempt := 0; ; attempt++ {
+		toAdd, toDelete, toUpdate, err := c.updateStreamServers(ctx, upstream, servers, cfg)
+		if errors.Is(err, ErrConfigReloaded) && attempt < cfg.reloadRetries {
+			continue
+		}
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("updating stream servers of %v upstream: %w", upstream, err)
 		}
+		return toAdd, toDelete, toUpdate, nil
 	}
+}
 
-	for _, server := range toDelete {
-		err := c.DeleteStreamServer(ctx, upstream, server.Server)
+func (c Client) updateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer, cfg updateConfig) ([]StreamUpstreamServer, []StreamUpstreamServer, []StreamUpstreamServer, error) {
+	var generation int
+	if cfg.checkReload {
+		info, err := c.GetNginxInfo(ctx)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("updating stream servers of %v upstream: %w", upstream, err)
+			return nil, nil, nil, err
 		}
+		generation = info.Generation
+	}
+
+	serversInNginx, err := c.GetStreamServers(ctx, upstream)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	for _, server := range toUpdate {
-		err := c.UpdateStreamServer(ctx, upstream, server)
+	toAdd, toDelete, toUpdate, err := DetermineStreamServerUpdates(servers, serversInNginx, WithDefaultPort(c.serverPort()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cfg.dryRun {
+		return toAdd, toDelete, toUpdate, nil
+	}
+	if cfg.checkConcurrent {
+		current, err := c.GetStreamServers(ctx, upstream)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("updating stream servers of %v upstream: %w", upstream, err)
+			return nil, nil, nil, err
+		}
+		if err := checkUnchanged(serversInNginx, current); err != nil {
+			return nil, nil, nil, err
 		}
 	}
+	if err := c.applyStreamServers(ctx, upstream, toAdd, toDelete, toUpdate, cfg); err != nil {
+		return nil, nil, nil, err
+	}
 
+	if cfg.checkReload {
+		if err := c.checkGeneration(ctx, generation); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 	return toAdd, toDelete, toUpdate, nil
 }
 
+// applyStreamServers adds, deletes and updates the servers of the stream upstream.
+// Servers of each kind of change are changed by up to the number
+// of requests configured with WithParallelism at once.
+func (c Client) applyStreamServers(ctx context.Context, upstream string, toAdd, toDelete, toUpdate []StreamUpstreamServer, cfg updateConfig) error {
+	if cfg.zeroDowntime {
+		return c.applyStreamServersWithoutDowntime(ctx, upstream, toAdd, toDelete, toUpdate, cfg)
+	}
+	addServer := func(server StreamUpstreamServer) error {
+		return c.AddStreamServer(ctx, upstream, server, WithoutExistenceCheck())
+	}
+	if err := forEach(toAdd, cfg.parallelism, addServer); err != nil {
+		return err
+	}
+	deleteServer := func(server StreamUpstreamServer) error {
+		return c.DeleteStreamServerByID(ctx, upstream, server.ID)
+	}
+	if err := forEach(toDelete, cfg.parallelism, deleteServer); err != nil {
+		return err
+	}
+	updateServer := func(server StreamUpstreamServer) error {
+		return c.UpdateStreamServer(ctx, upstream, server)
+	}
+	return forEach(toUpdate, cfg.parallelism, updateServer)
+}
+
+// applyStreamServersWithoutDowntime adds, updates and deletes the servers
+// of the stream upstream in the order configured with WithZeroDowntime.
+func (c Client) applyStreamServersWithoutDowntime(ctx context.Context, upstream string, toAdd, toDelete, toUpdate []StreamUpstreamServer, cfg updateConfig) error {
+	addServer := func(server StreamUpstreamServer) error {
+		return c.AddStreamServer(ctx, upstream, server, WithoutExistenceCheck())
+	}
+	if err := forEach(toAdd, cfg.parallelism, addServer); err != nil {
+		return err
+	}
+	var added []string
+	for _, server := range toAdd {
+		added = append(added, server.Server)
+	}
+	if err := c.waitForAddedServers(ctx, upstream, added, cfg, streamContext); err != nil {
+		return err
+	}
+	updateServer := func(server StreamUpstreamServer) error {
+		return c.UpdateStreamServer(ctx, upstream, server)
+	}
+	if err := forEach(toUpdate, cfg.parallelism, updateServer); err != nil {
+		return err
+	}
+	deleteServer := func(server StreamUpstreamServer) error {
+		return c.DeleteStreamServerByID(ctx, upstream, server.ID)
+	}
+	return forEach(toDelete, cfg.parallelism, deleteServer)
+}
+
 func (c Client) getIDOfStreamServer(ctx context.Context, upstream string, name string) (int, error) {
 	servers, err := c.GetStreamServers(ctx, upstream)
 	if err != nil {
@@ -831,8 +2160,218 @@ func (c Client) getIDOfStreamServer(ctx context.Context, upstream string, name s
 	return -1, nil
 }
 
+// Names of the stats sections retrieved by GetStats and GetStatsStream.
+const (
+	SectionNginxInfo              = "nginx"
+	SectionCaches                 = "caches"
+	SectionProcesses              = "processes"
+	SectionSlabs                  = "slabs"
+	SectionConnections            = "connections"
+	SectionHTTPRequests           = "http_requests"
+	SectionSSL                    = "ssl"
+	SectionServerZones            = "server_zones"
+	SectionUpstreams              = "upstreams"
+	SectionStreamServerZones      = "stream_server_zones"
+	SectionStreamUpstreams        = "stream_upstreams"
+	SectionStreamZoneSync         = "stream_zone_sync"
+	SectionLocationZones          = "location_zones"
+	SectionResolvers              = "resolvers"
+	SectionHTTPLimitRequests      = "http_limit_requests"
+	SectionHTTPLimitConnections   = "http_limit_connections"
+	SectionStreamLimitConnections = "stream_limit_connections"
+	SectionWorkers                = "workers"
+)
+
+// StatsSection represents a single stats section delivered by GetStatsStream.
+// Value holds the section stats, for example Connections for
+// the SectionConnections, or the value created by the SectionFactory
+// for custom sections. Value is nil when Err is not nil.
+type StatsSection struct {
+	Name  string
+	Value interface{}
+	Err   error
+}
+
+type statsSection struct {
+	name  string
+	fetch func(context.Context) (interface{}, error)
+	set   func(*Stats, interface{})
+}
+
+// statsSections returns all sections that make up Stats,
+// including registered custom sections.
+func (c Client) statsSections() []statsSection {
+	sections := []statsSection{
+		{
+			name:  SectionNginxInfo,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetNginxInfo(ctx) },
+			set:   func(s *Stats, v interface{}) { s.NginxInfo = v.(NginxInfo) },
+		},
+		{
+			name:  SectionCaches,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetCaches(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Caches = v.(Caches) },
+		},
+		{
+			name:  SectionProcesses,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetProcesses(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Processes = v.(Processes) },
+		},
+		{
+			name:  SectionSlabs,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetSlabs(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Slabs = v.(Slabs) },
+		},
+		{
+			name:  SectionConnections,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetConnections(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Connections = v.(Connections) },
+		},
+		{
+			name:  SectionHTTPRequests,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetHTTPRequests(ctx) },
+			set:   func(s *Stats, v interface{}) { s.HTTPRequests = v.(HTTPRequests) },
+		},
+		{
+			name:  SectionSSL,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetSSL(ctx) },
+			set:   func(s *Stats, v interface{}) { s.SSL = v.(SSL) },
+		},
+		{
+			name:  SectionServerZones,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetServerZones(ctx) },
+			set:   func(s *Stats, v interface{}) { s.ServerZones = v.(ServerZones) },
+		},
+		{
+			name:  SectionUpstreams,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetUpstreams(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Upstreams = v.(Upstreams) },
+		},
+		{
+			name: SectionStreamServerZones,
+			fetch: func(ctx context.Context) (interface{}, error) {
+				return emptyIfUnavailable(c.GetStreamServerZones(ctx))
+			},
+			set: func(s *Stats, v interface{}) { s.StreamServerZones = v.(StreamServerZones) },
+		},
+		{
+			name:  SectionStreamUpstreams,
+			fetch: func(ctx context.Context) (interface{}, error) { return emptyIfUnavailable(c.GetStreamUpstreams(ctx)) },
+			set:   func(s *Stats, v interface{}) { s.StreamUpstreams = v.(StreamUpstreams) },
+		},
+		{
+			name:  SectionStreamZoneSync,
+			fetch: func(ctx context.Context) (interface{}, error) { return emptyIfUnavailable(c.GetStreamZoneSync(ctx)) },
+			set:   func(s *Stats, v interface{}) { s.StreamZoneSync = v.(StreamZoneSync) },
+		},
+		{
+			name:  SectionLocationZones,
+			fetch: func(ctx context.Context) (interface{}, error) { return emptyIfUnsupported(c.GetLocationZones(ctx)) },
+			set:   func(s *Stats, v interface{}) { s.LocationZones = v.(LocationZones) },
+		},
+		{
+			name:  SectionResolvers,
+			fetch: func(ctx context.Context) (interface{}, error) { return emptyIfUnsupported(c.GetResolvers(ctx)) },
+			set:   func(s *Stats, v interface{}) { s.Resolvers = v.(Resolvers) },
+		},
+		{
+			name:  SectionHTTPLimitRequests,
+			fetch: func(ctx context.Context) (interface{}, error) { return emptyIfUnsupported(c.GetHTTPLimitReqs(ctx)) },
+			set:   func(s *Stats, v interface{}) { s.HTTPLimitRequests = v.(HTTPLimitRequests) },
+		},
+		{
+			name: SectionHTTPLimitConnections,
+			fetch: func(ctx context.Context) (interface{}, error) {
+				return emptyIfUnsupported(c.GetHTTPConnectionsLimit(ctx))
+			},
+			set: func(s *Stats, v interface{}) { s.HTTPLimitConnections = v.(HTTPLimitConnections) },
+		},
+		{
+			name: SectionStreamLimitConnections,
+			fetch: func(ctx context.Context) (interface{}, error) {
+				return emptyIfUnavailable(c.GetStreamConnectionsLimit(ctx))
+			},
+			set: func(s *Stats, v interface{}) { s.StreamLimitConnections = v.(StreamLimitConnections) },
+		},
+	}
+	if c.version >= workersAPIVersion {
+		sections = append(sections, statsSection{
+			name:  SectionWorkers,
+			fetch: func(ctx context.Context) (interface{}, error) { return c.GetWorkers(ctx) },
+			set:   func(s *Stats, v interface{}) { s.Workers = v.(Workers) },
+		})
+	}
+	for _, cs := range registeredSections() {
+		cs := cs
+		sections = append(sections, statsSection{
+			name: cs.name,
+			fetch: func(ctx context.Context) (interface{}, error) {
+				v := cs.factory()
+				if err := c.get(ctx, cs.path, v); err != nil {
+					return nil, fmt.Errorf("getting %s section: %w", cs.name, err)
+				}
+				return v, nil
+			},
+			set: func(s *Stats, v interface{}) {
+				if s.Extensions == nil {
+					s.Extensions = make(map[string]interface{})
+				}
+				s.Extensions[cs.name] = v
+			},
+		})
+	}
+	return sections
+}
+
+// emptyIfUnavailable returns the zero value of a stream stats section
+// instead of the error when NGINX has no stream block, so the section
+// is not found, or when the section is not supported by the version
+// of NGINX API.
+func emptyIfUnavailable[T any](v T, err error) (interface{}, error) {
+	if isPathNotFound(err) {
+		var empty T
+		return empty, nil
+	}
+	return emptyIfUnsupported(v, err)
+}
+
+// emptyIfUnsupported returns the zero value of the stats section instead
+// of the error when the section is not supported by the version of NGINX API.
+// HTTP sections are always present in supported versions, so any other
+// error, including a missing path, is returned.
+func emptyIfUnsupported[T any](v T, err error) (interface{}, error) {
+	if errors.Is(err, ErrUnsupportedAPIVersion) {
+		var empty T
+		return empty, nil
+	}
+	return v, err
+}
+
+// sectionContext returns the context for fetching one of the outstanding
+// stats sections. When ctx has a deadline, the remaining time is divided
+// evenly between the outstanding sections, so a single slow section can't
+// use up the whole deadline. Time not used by a section is carried over to
+// the next ones. The timeout configured with WithSectionTimeout caps
+// the time available for a section.
+func (c Client) sectionContext(ctx context.Context, outstanding int) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok && outstanding > 0 {
+		timeout = time.Until(deadline) / time.Duration(outstanding)
+	}
+	if c.sectionTimeout > 0 && (timeout == 0 || c.sectionTimeout < timeout) {
+		timeout = c.sectionTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // GetStats gets process, slab, connection, request, ssl, zone, stream zone,
 // upstream and stream upstream related stats from the NGINX Plus API.
+//
+// Sections are fetched one after another. If ctx has a deadline,
+// each section gets its share of the remaining time.
 func (c Client) GetStats(ctx context.Context) (_ Stats, err error) {
 	defer func() {
 		if err != nil {
@@ -840,110 +2379,94 @@ func (c Client) GetStats(ctx context.Context) (_ Stats, err error) {
 		}
 	}()
 
-	info, err := c.GetNginxInfo(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	caches, err := c.GetCaches(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	processes, err := c.GetProcesses(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	slabs, err := c.GetSlabs(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	cons, err := c.GetConnections(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	requests, err := c.GetHTTPRequests(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	ssl, err := c.GetSSL(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	zones, err := c.GetServerZones(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	upstreams, err := c.GetUpstreams(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	streamZones, err := c.GetStreamServerZones(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	streamUpstreams, err := c.GetStreamUpstreams(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	streamZoneSync, err := c.GetStreamZoneSync(ctx)
-	if err != nil {
-		return Stats{}, err
+	var stats Stats
+	sections := c.statsSections()
+	for i, s := range sections {
+		sctx, cancel := c.sectionContext(ctx, len(sections)-i)
+		v, err := s.fetch(sctx)
+		cancel()
+		if err != nil {
+			return Stats{}, err
+		}
+		s.set(&stats, v)
+	}
+	return stats, nil
+}
+
+// GetStatsStream fetches all stats sections concurrently and delivers each
+// section on the returned channel as soon as it is retrieved, so fast
+// sections don't wait for the slow ones. The channel is closed after
+// all sections are delivered.
+func (c Client) GetStatsStream(ctx context.Context) <-chan StatsSection {
+	sections := c.statsSections()
+	ch := make(chan StatsSection, len(sections))
+	var wg sync.WaitGroup
+	for _, s := range sections {
+		wg.Add(1)
+		go func(s statsSection) {
+			defer wg.Done()
+			sctx, cancel := c.sectionContext(ctx, 1)
+			defer cancel()
+			v, err := s.fetch(sctx)
+			if err != nil {
+				v = nil
+			}
+			ch <- StatsSection{Name: s.name, Value: v, Err: err}
+		}(s)
 	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
 
-	locationZones, err := c.GetLocationZones(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
+// SectionFactory returns a pointer to a new value that the response
+// of a custom stats section is decoded into.
+type SectionFactory func() interface{}
 
-	resolvers, err := c.GetResolvers(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
+type customSection struct {
+	name    string
+	path    string
+	factory SectionFactory
+}
 
-	limitReqs, err := c.GetHTTPLimitReqs(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
+var (
+	sectionsMu     sync.RWMutex
+	customSections []customSection
+)
 
-	limitConnsHTTP, err := c.GetHTTPConnectionsLimit(ctx)
-	if err != nil {
-		return Stats{}, err
+// RegisterSection registers a custom stats section. GetStats fetches the section
+// from the given API path, for example "http/new_endpoint", decodes it into
+// the value returned by the factory and stores it in Stats.Extensions
+// under the section name. It allows to collect stats from API endpoints
+// that are not yet supported by the library.
+func RegisterSection(name, path string, factory SectionFactory) error {
+	if name == "" {
+		return errors.New("empty section name")
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return errors.New("empty section path")
+	}
+	if factory == nil {
+		return errors.New("nil section factory")
+	}
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	for _, s := range customSections {
+		if s.name == name {
+			return fmt.Errorf("section %s already registered", name)
+		}
 	}
+	customSections = append(customSections, customSection{name: name, path: path, factory: factory})
+	return nil
+}
 
-	limitConnsStream, err := c.GetStreamConnectionsLimit(ctx)
-	if err != nil {
-		return Stats{}, err
-	}
-
-	return Stats{
-		NginxInfo:              info,
-		Caches:                 caches,
-		Processes:              processes,
-		Slabs:                  slabs,
-		Connections:            cons,
-		HTTPRequests:           requests,
-		SSL:                    ssl,
-		ServerZones:            zones,
-		StreamServerZones:      streamZones,
-		Upstreams:              upstreams,
-		StreamUpstreams:        streamUpstreams,
-		StreamZoneSync:         streamZoneSync,
-		LocationZones:          locationZones,
-		Resolvers:              resolvers,
-		HTTPLimitRequests:      limitReqs,
-		HTTPLimitConnections:   limitConnsHTTP,
-		StreamLimitConnections: limitConnsStream,
-	}, nil
+func registeredSections() []customSection {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	return slices.Clone(customSections)
 }
 
 func isNGINXStatusFieldValid(fields []string) error {
@@ -957,122 +2480,259 @@ func isNGINXStatusFieldValid(fields []string) error {
 }
 
 // GetCaches returns Cache stats
-func (c Client) GetCaches(ctx context.Context) (Caches, error) {
+func (c Client) GetCaches(ctx context.Context, opts ...requestOption) (Caches, error) {
 	var caches Caches
-	if err := c.get(ctx, "http/caches", &caches); err != nil {
+	if err := c.get(ctx, "http/caches", &caches, opts...); err != nil {
 		return nil, fmt.Errorf("getting caches: %w", err)
 	}
 	return caches, nil
 }
 
+// GetCache returns stats of the cache zone.
+func (c Client) GetCache(ctx context.Context, cache string, opts ...requestOption) (HTTPCache, error) {
+	if cache == "" {
+		return HTTPCache{}, errors.New("missing cache zone")
+	}
+	var httpCache HTTPCache
+	if err := c.get(ctx, fmt.Sprintf("http/caches/%v", cache), &httpCache, opts...); err != nil {
+		return HTTPCache{}, fmt.Errorf("getting cache %v: %w", cache, err)
+	}
+	return httpCache, nil
+}
+
 // GetSlabs returns Slabs stats.
-func (c Client) GetSlabs(ctx context.Context) (Slabs, error) {
+func (c Client) GetSlabs(ctx context.Context, opts ...requestOption) (Slabs, error) {
 	var slabs Slabs
-	if err := c.get(ctx, "slabs", &slabs); err != nil {
+	if err := c.get(ctx, "slabs", &slabs, opts...); err != nil {
 		return nil, fmt.Errorf("getting slabs: %w", err)
 	}
 	return slabs, nil
 }
 
+// GetSlab returns stats of the shared memory zone.
+func (c Client) GetSlab(ctx context.Context, zone string, opts ...requestOption) (Slab, error) {
+	if zone == "" {
+		return Slab{}, errors.New("missing zone")
+	}
+	var slab Slab
+	if err := c.get(ctx, fmt.Sprintf("slabs/%v", zone), &slab, opts...); err != nil {
+		return Slab{}, fmt.Errorf("getting slab %v: %w", zone, err)
+	}
+	return slab, nil
+}
+
 // GetConnections returns Connections stats.
-func (c Client) GetConnections(ctx context.Context) (Connections, error) {
+func (c Client) GetConnections(ctx context.Context, opts ...requestOption) (Connections, error) {
 	var cons Connections
-	if err := c.get(ctx, "connections", &cons); err != nil {
+	if err := c.get(ctx, "connections", &cons, opts...); err != nil {
 		return Connections{}, fmt.Errorf("failed to get connections: %w", err)
 	}
 	return cons, nil
 }
 
 // GetHTTPRequests returns http/requests stats.
-func (c Client) GetHTTPRequests(ctx context.Context) (HTTPRequests, error) {
+func (c Client) GetHTTPRequests(ctx context.Context, opts ...requestOption) (HTTPRequests, error) {
 	var requests HTTPRequests
-	if err := c.get(ctx, "http/requests", &requests); err != nil {
+	if err := c.get(ctx, "http/requests", &requests, opts...); err != nil {
 		return HTTPRequests{}, fmt.Errorf("getting http requests: %w", err)
 	}
 	return requests, nil
 }
 
-// GetSSL returns SSL stats.
-func (c Client) GetSSL(ctx context.Context) (SSL, error) {
+// GetSSL returns SSL stats. Versions of NGINX Plus API older than 8
+// report only the handshakes and session reuses counters, so the other
+// counters are zero.
+func (c Client) GetSSL(ctx context.Context, opts ...requestOption) (SSL, error) {
 	var ssl SSL
-	if err := c.get(ctx, "ssl", &ssl); err != nil {
+	if err := c.get(ctx, "ssl", &ssl, opts...); err != nil {
 		return SSL{}, fmt.Errorf("getting ssl: %w", err)
 	}
 	return ssl, nil
 }
 
 // GetServerZones returns http/server_zones stats.
-func (c *Client) GetServerZones(ctx context.Context) (ServerZones, error) {
+func (c *Client) GetServerZones(ctx context.Context, opts ...requestOption) (ServerZones, error) {
 	var zones ServerZones
-	if err := c.get(ctx, "http/server_zones", &zones); err != nil {
+	if err := c.get(ctx, "http/server_zones", &zones, opts...); err != nil {
 		return nil, fmt.Errorf("getting server zones: %w", err)
 	}
 	return zones, nil
 }
 
+// GetServerZone returns stats of the HTTP server zone.
+func (c Client) GetServerZone(ctx context.Context, zone string, opts ...requestOption) (ServerZone, error) {
+	if zone == "" {
+		return ServerZone{}, errors.New("missing zone")
+	}
+	var serverZone ServerZone
+	if err := c.get(ctx, fmt.Sprintf("http/server_zones/%v", zone), &serverZone, opts...); err != nil {
+		return ServerZone{}, fmt.Errorf("getting server zone %v: %w", zone, err)
+	}
+	return serverZone, nil
+}
+
 // GetStreamServerZones returns stream/server_zones stats.
-func (c Client) GetStreamServerZones(ctx context.Context) (StreamServerZones, error) {
+func (c Client) GetStreamServerZones(ctx context.Context, opts ...requestOption) (StreamServerZones, error) {
 	var zones StreamServerZones
-	err := c.get(ctx, "stream/server_zones", &zones)
+	err := c.get(ctx, "stream/server_zones", &zones, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("getting stream server zones: %w", err)
 	}
 	return zones, err
 }
 
+// GetStreamServerZone returns stats of the stream server zone.
+func (c Client) GetStreamServerZone(ctx context.Context, zone string, opts ...requestOption) (StreamServerZone, error) {
+	if zone == "" {
+		return StreamServerZone{}, errors.New("missing zone")
+	}
+	var serverZone StreamServerZone
+	if err := c.get(ctx, fmt.Sprintf("stream/server_zones/%v", zone), &serverZone, opts...); err != nil {
+		return StreamServerZone{}, fmt.Errorf("getting stream server zone %v: %w", zone, err)
+	}
+	return serverZone, nil
+}
+
 // GetUpstreams returns http/upstreams stats.
-func (c Client) GetUpstreams(ctx context.Context) (Upstreams, error) {
+func (c Client) GetUpstreams(ctx context.Context, opts ...requestOption) (Upstreams, error) {
 	var upstreams Upstreams
-	if err := c.get(ctx, "http/upstreams", &upstreams); err != nil {
+	if err := c.get(ctx, "http/upstreams", &upstreams, opts...); err != nil {
 		return nil, fmt.Errorf("getting upstreams: %w", err)
 	}
 	return upstreams, nil
 }
 
+// GetUpstream returns stats of the HTTP upstream, including its peers, queue and zombies.
+func (c Client) GetUpstream(ctx context.Context, upstream string, opts ...requestOption) (Upstream, error) {
+	if upstream == "" {
+		return Upstream{}, errors.New("missing upstream")
+	}
+	var u Upstream
+	if err := c.get(ctx, fmt.Sprintf("http/upstreams/%v", upstream), &u, opts...); err != nil {
+		return Upstream{}, fmt.Errorf("getting upstream %v: %w", upstream, err)
+	}
+	return u, nil
+}
+
 // GetStreamUpstreams returns stream/upstreams stats.
-func (c Client) GetStreamUpstreams(ctx context.Context) (StreamUpstreams, error) {
+func (c Client) GetStreamUpstreams(ctx context.Context, opts ...requestOption) (StreamUpstreams, error) {
 	var upstreams StreamUpstreams
-	err := c.get(ctx, "stream/upstreams", &upstreams)
+	err := c.get(ctx, "stream/upstreams", &upstreams, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("getting stream upstreams: %w", err)
 	}
 	return upstreams, nil
 }
 
-// GetStreamZoneSync returns stream/zone_sync stats.
-func (c Client) GetStreamZoneSync(ctx context.Context) (StreamZoneSync, error) {
-	var streamZoneSync StreamZoneSync
-	err := c.get(ctx, "stream/zone_sync", &streamZoneSync)
-	if err != nil {
-		return StreamZoneSync{}, fmt.Errorf("getting stream zone sync: %w", err)
+// GetStreamUpstream returns stats of the stream upstream, including its peers and zombies.
+func (c Client) GetStreamUpstream(ctx context.Context, upstream string, opts ...requestOption) (StreamUpstream, error) {
+	if upstream == "" {
+		return StreamUpstream{}, errors.New("missing upstream")
+	}
+	var u StreamUpstream
+	if err := c.get(ctx, fmt.Sprintf("stream/upstreams/%v", upstream), &u, opts...); err != nil {
+		return StreamUpstream{}, fmt.Errorf("getting stream upstream %v: %w", upstream, err)
+	}
+	return u, nil
+}
+
+// GetStreamZoneSync returns stream/zone_sync stats.
+func (c Client) GetStreamZoneSync(ctx context.Context, opts ...requestOption) (StreamZoneSync, error) {
+	var streamZoneSync StreamZoneSync
+	err := c.get(ctx, "stream/zone_sync", &streamZoneSync, opts...)
+	if err != nil {
+		return StreamZoneSync{}, fmt.Errorf("getting stream zone sync: %w", err)
+	}
+	return streamZoneSync, nil
+}
+
+// GetStreamZoneSyncStatus returns stream/zone_sync/status stats,
+// the synchronization status of the cluster node, without
+// the stats of every synchronized zone.
+func (c Client) GetStreamZoneSyncStatus(ctx context.Context) (StreamZoneSyncStatus, error) {
+	var status StreamZoneSyncStatus
+	if err := c.get(ctx, "stream/zone_sync/status", &status); err != nil {
+		return StreamZoneSyncStatus{}, fmt.Errorf("getting stream zone sync status: %w", err)
+	}
+	return status, nil
+}
+
+// GetStreamZoneSyncZones returns stream/zone_sync/zones stats,
+// the synchronization status of shared memory zones by zone name.
+func (c Client) GetStreamZoneSyncZones(ctx context.Context) (map[string]SyncZone, error) {
+	var zones map[string]SyncZone
+	if err := c.get(ctx, "stream/zone_sync/zones", &zones); err != nil {
+		return nil, fmt.Errorf("getting stream zone sync zones: %w", err)
+	}
+	return zones, nil
+}
+
+// GetStreamZoneSyncZone returns the synchronization status of the shared memory zone.
+func (c Client) GetStreamZoneSyncZone(ctx context.Context, zone string) (SyncZone, error) {
+	if zone == "" {
+		return SyncZone{}, errors.New("missing zone")
 	}
-	return streamZoneSync, nil
+	var syncZone SyncZone
+	if err := c.get(ctx, fmt.Sprintf("stream/zone_sync/zones/%v", zone), &syncZone); err != nil {
+		return SyncZone{}, fmt.Errorf("getting stream zone sync zone %v: %w", zone, err)
+	}
+	return syncZone, nil
 }
 
 // GetLocationZones returns http/location_zones stats.
-func (c Client) GetLocationZones(ctx context.Context) (LocationZones, error) {
+func (c Client) GetLocationZones(ctx context.Context, opts ...requestOption) (LocationZones, error) {
 	var locationZones LocationZones
-	if c.version < 5 {
-		return LocationZones{}, nil
+	if err := c.requireVersion(5, opts...); err != nil {
+		return nil, fmt.Errorf("getting location zones: %w", err)
 	}
-	if err := c.get(ctx, "http/location_zones", &locationZones); err != nil {
-		return nil, fmt.Errorf("gettign location zones: %w", err)
+	if err := c.get(ctx, "http/location_zones", &locationZones, opts...); err != nil {
+		return nil, fmt.Errorf("getting location zones: %w", err)
 	}
 	return locationZones, nil
 }
 
+// GetLocationZone returns stats of the location zone.
+func (c Client) GetLocationZone(ctx context.Context, zone string, opts ...requestOption) (LocationZone, error) {
+	if zone == "" {
+		return LocationZone{}, errors.New("missing zone")
+	}
+	if err := c.requireVersion(5, opts...); err != nil {
+		return LocationZone{}, fmt.Errorf("getting location zone %v: %w", zone, err)
+	}
+	var locationZone LocationZone
+	if err := c.get(ctx, fmt.Sprintf("http/location_zones/%v", zone), &locationZone, opts...); err != nil {
+		return LocationZone{}, fmt.Errorf("getting location zone %v: %w", zone, err)
+	}
+	return locationZone, nil
+}
+
 // GetResolvers returns Resolvers stats.
-func (c Client) GetResolvers(ctx context.Context) (Resolvers, error) {
+func (c Client) GetResolvers(ctx context.Context, opts ...requestOption) (Resolvers, error) {
 	var resolvers Resolvers
-	if c.version < 5 {
-		return Resolvers{}, nil
+	if err := c.requireVersion(5, opts...); err != nil {
+		return nil, fmt.Errorf("getting resolvers: %w", err)
 	}
-	if err := c.get(ctx, "resolvers", &resolvers); err != nil {
+	if err := c.get(ctx, "resolvers", &resolvers, opts...); err != nil {
 		return nil, fmt.Errorf("getting resolvers: %w", err)
 	}
 	return resolvers, nil
 }
 
+// GetResolver returns stats of the resolver zone.
+func (c Client) GetResolver(ctx context.Context, zone string, opts ...requestOption) (Resolver, error) {
+	if zone == "" {
+		return Resolver{}, errors.New("missing zone")
+	}
+	if err := c.requireVersion(5, opts...); err != nil {
+		return Resolver{}, fmt.Errorf("getting resolver %v: %w", zone, err)
+	}
+	var resolver Resolver
+	if err := c.get(ctx, fmt.Sprintf("resolvers/%v", zone), &resolver, opts...); err != nil {
+		return Resolver{}, fmt.Errorf("getting resolver %v: %w", zone, err)
+	}
+	return resolver, nil
+}
+
 // GetProcesses returns Processes stats.
 func (c Client) GetProcesses(ctx context.Context) (Processes, error) {
 	var respProcesses struct {
@@ -1087,6 +2747,50 @@ func (c Client) GetProcesses(ctx context.Context) (Processes, error) {
 	return p, nil
 }
 
+// GetWorkers returns stats of NGINX worker processes.
+// Workers stats are available starting from version 9 of NGINX Plus API.
+func (c Client) GetWorkers(ctx context.Context) (Workers, error) {
+	if err := c.requireVersion(workersAPIVersion); err != nil {
+		return nil, fmt.Errorf("ngx: getting workers: %w", err)
+	}
+	var workers Workers
+	if err := c.get(ctx, "workers", &workers); err != nil {
+		return nil, fmt.Errorf("ngx: getting workers: %w", err)
+	}
+	return workers, nil
+}
+
+// GetLicense returns license information of NGINX Plus, including
+// the time the license expires at. License information is available
+// starting from version 9 of NGINX Plus API.
+func (c Client) GetLicense(ctx context.Context) (License, error) {
+	if err := c.requireVersion(licenseAPIVersion); err != nil {
+		return License{}, fmt.Errorf("ngx: getting license: %w", err)
+	}
+	var respLicense struct {
+		ActiveTill int64 `json:"active_till"`
+		Eval       bool  `json:"eval"`
+		Reporting  struct {
+			Healthy bool   `json:"healthy"`
+			Fails   uint64 `json:"fails"`
+			Grace   int64  `json:"grace"`
+		} `json:"reporting"`
+	}
+	if err := c.get(ctx, "license", &respLicense); err != nil {
+		return License{}, fmt.Errorf("ngx: getting license: %w", err)
+	}
+	l := License{
+		ActiveTill: time.Unix(respLicense.ActiveTill, 0).UTC(),
+		Eval:       respLicense.Eval,
+		Reporting: LicenseReporting{
+			Healthy: respLicense.Reporting.Healthy,
+			Fails:   respLicense.Reporting.Fails,
+			Grace:   time.Duration(respLicense.Reporting.Grace) * time.Second,
+		},
+	}
+	return l, nil
+}
+
 // KeyValPairs are the key-value pairs stored in a zone.
 type KeyValPairs map[string]string
 
@@ -1119,6 +2823,39 @@ func (c Client) getKeyValPairs(ctx context.Context, zone string, stream bool) (K
 	return keyValPairs, nil
 }
 
+// GetKeyValPair fetches the value of the key in a given HTTP zone.
+func (c Client) GetKeyValPair(ctx context.Context, zone string, key string) (string, error) {
+	return c.getKeyValPair(ctx, zone, key, httpContext)
+}
+
+// GetStreamKeyValPair fetches the value of the key in a given Stream zone.
+func (c Client) GetStreamKeyValPair(ctx context.Context, zone string, key string) (string, error) {
+	return c.getKeyValPair(ctx, zone, key, streamContext)
+}
+
+func (c Client) getKeyValPair(ctx context.Context, zone string, key string, stream bool) (string, error) {
+	if zone == "" {
+		return "", errors.New("missing zone")
+	}
+	if key == "" {
+		return "", errors.New("missing key")
+	}
+	base := "http"
+	if stream {
+		base = "stream"
+	}
+	path := fmt.Sprintf("%v/keyvals/%v?key=%v", base, zone, url.QueryEscape(key))
+	var keyValPairs KeyValPairs
+	if err := c.get(ctx, path, &keyValPairs); err != nil {
+		return "", fmt.Errorf("getting key %v for %v/%v zone: %w", key, base, zone, err)
+	}
+	val, ok := keyValPairs[key]
+	if !ok {
+		return "", fmt.Errorf("key %v not found in %v/%v zone", key, base, zone)
+	}
+	return val, nil
+}
+
 // GetAllKeyValPairs fetches all key/value pairs for all HTTP zones.
 func (c Client) GetAllKeyValPairs(ctx context.Context) (KeyValPairsByZone, error) {
 	return c.getAllKeyValPairs(ctx, httpContext)
@@ -1251,12 +2988,42 @@ func (c Client) deleteKeyValPairs(ctx context.Context, zone string, stream bool)
 	return nil
 }
 
+// DiffKeyValPairs compares desired key-value pairs of a zone with pairs
+// stored in NGINX. It returns pairs to add, pairs with values to modify,
+// and pairs to delete with their current values. It doesn't make any API calls.
+func DiffKeyValPairs(desired, actual KeyValPairs) (toAdd, toModify, toDelete KeyValPairs) {
+	for key, val := range desired {
+		current, ok := actual[key]
+		switch {
+		case !ok:
+			if toAdd == nil {
+				toAdd = make(KeyValPairs)
+			}
+			toAdd[key] = val
+		case current != val:
+			if toModify == nil {
+				toModify = make(KeyValPairs)
+			}
+			toModify[key] = val
+		}
+	}
+	for key, val := range actual {
+		if _, ok := desired[key]; !ok {
+			if toDelete == nil {
+				toDelete = make(KeyValPairs)
+			}
+			toDelete[key] = val
+		}
+	}
+	return toAdd, toModify, toDelete
+}
+
 // UpdateHTTPServer updates the server of the upstream.
 func (c Client) UpdateHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
 	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, server.ID)
 	server.ID = 0
 	if err := c.patch(ctx, path, &server, http.StatusOK); err != nil {
-		return fmt.Errorf("ngx: updating %v server to %v upstream: %w", server.Server, upstream, err)
+		return fmt.Errorf("ngx: updating %v server to %v upstream: %w", server.Server, upstream, serverError(err))
 	}
 	return nil
 }
@@ -1266,133 +3033,492 @@ func (c Client) UpdateStreamServer(ctx context.Context, upstream string, server
 	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, server.ID)
 	server.ID = 0
 	if err := c.patch(ctx, path, &server, http.StatusOK); err != nil {
-		return fmt.Errorf("ngx: updating %v stream server to %v upstream: %w", server.Server, upstream, err)
+		return fmt.Errorf("ngx: updating %v stream server to %v upstream: %w", server.Server, upstream, serverError(err))
 	}
 	return nil
 }
 
 // GetHTTPLimitReqs returns http/limit_reqs stats.
-func (c Client) GetHTTPLimitReqs(ctx context.Context) (HTTPLimitRequests, error) {
+func (c Client) GetHTTPLimitReqs(ctx context.Context, opts ...requestOption) (HTTPLimitRequests, error) {
 	var limitReqs HTTPLimitRequests
-	if c.version < 6 {
-		return HTTPLimitRequests{}, nil
+	if err := c.requireVersion(6, opts...); err != nil {
+		return nil, fmt.Errorf("ngx: getting http limit requests: %w", err)
 	}
-	if err := c.get(ctx, "http/limit_reqs", &limitReqs); err != nil {
+	if err := c.get(ctx, "http/limit_reqs", &limitReqs, opts...); err != nil {
 		return nil, fmt.Errorf("ngx: getting http limit requests: %w", err)
 	}
 	return limitReqs, nil
 }
 
+// GetHTTPLimitReq returns stats of the HTTP requests limit zone.
+func (c Client) GetHTTPLimitReq(ctx context.Context, zone string, opts ...requestOption) (HTTPLimitRequest, error) {
+	if zone == "" {
+		return HTTPLimitRequest{}, errors.New("missing zone")
+	}
+	if err := c.requireVersion(6, opts...); err != nil {
+		return HTTPLimitRequest{}, fmt.Errorf("getting limit requests zone %v: %w", zone, err)
+	}
+	var limitReq HTTPLimitRequest
+	if err := c.get(ctx, fmt.Sprintf("http/limit_reqs/%v", zone), &limitReq, opts...); err != nil {
+		return HTTPLimitRequest{}, fmt.Errorf("getting limit requests zone %v: %w", zone, err)
+	}
+	return limitReq, nil
+}
+
 // GetHTTPConnectionsLimit returns http/limit_conns stats.
-func (c Client) GetHTTPConnectionsLimit(ctx context.Context) (HTTPLimitConnections, error) {
+func (c Client) GetHTTPConnectionsLimit(ctx context.Context, opts ...requestOption) (HTTPLimitConnections, error) {
 	var limitConns HTTPLimitConnections
-	if c.version < 6 {
-		return HTTPLimitConnections{}, nil
+	if err := c.requireVersion(6, opts...); err != nil {
+		return nil, fmt.Errorf("ngx: getting http connections limit: %w", err)
 	}
-	if err := c.get(ctx, "http/limit_conns", &limitConns); err != nil {
+	if err := c.get(ctx, "http/limit_conns", &limitConns, opts...); err != nil {
 		return nil, fmt.Errorf("ngx: getting http connections limit: %w", err)
 	}
 	return limitConns, nil
 }
 
+// GetHTTPLimitConn returns stats of the HTTP connections limit zone.
+func (c Client) GetHTTPLimitConn(ctx context.Context, zone string, opts ...requestOption) (LimitConnection, error) {
+	if zone == "" {
+		return LimitConnection{}, errors.New("missing zone")
+	}
+	if err := c.requireVersion(6, opts...); err != nil {
+		return LimitConnection{}, fmt.Errorf("getting limit connections zone %v: %w", zone, err)
+	}
+	var limitConn LimitConnection
+	if err := c.get(ctx, fmt.Sprintf("http/limit_conns/%v", zone), &limitConn, opts...); err != nil {
+		return LimitConnection{}, fmt.Errorf("getting limit connections zone %v: %w", zone, err)
+	}
+	return limitConn, nil
+}
+
 // GetStreamConnectionsLimit returns stream/limit_conns stats.
-func (c Client) GetStreamConnectionsLimit(ctx context.Context) (StreamLimitConnections, error) {
+func (c Client) GetStreamConnectionsLimit(ctx context.Context, opts ...requestOption) (StreamLimitConnections, error) {
 	var limitConns StreamLimitConnections
-	if c.version < 6 {
-		return StreamLimitConnections{}, nil
+	if err := c.requireVersion(6, opts...); err != nil {
+		return nil, fmt.Errorf("ngx: getting stream connections limit: %w", err)
 	}
-	if err := c.get(ctx, "stream/limit_conns", &limitConns); err != nil {
+	if err := c.get(ctx, "stream/limit_conns", &limitConns, opts...); err != nil {
 		return nil, fmt.Errorf("ngx: getting stream connections limit: %w", err)
 	}
 	return limitConns, nil
 }
 
-func (c Client) get(ctx context.Context, path string, data interface{}) error {
-	url := fmt.Sprintf("%v/%v/%v", c.URL, c.version, path)
+// GetStreamLimitConn returns stats of the stream connections limit zone.
+func (c Client) GetStreamLimitConn(ctx context.Context, zone string, opts ...requestOption) (LimitConnection, error) {
+	if zone == "" {
+		return LimitConnection{}, errors.New("missing zone")
+	}
+	if err := c.requireVersion(6, opts...); err != nil {
+		return LimitConnection{}, fmt.Errorf("getting stream limit connections zone %v: %w", zone, err)
+	}
+	var limitConn LimitConnection
+	if err := c.get(ctx, fmt.Sprintf("stream/limit_conns/%v", zone), &limitConn, opts...); err != nil {
+		return LimitConnection{}, fmt.Errorf("getting stream limit connections zone %v: %w", zone, err)
+	}
+	return limitConn, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func (c Client) get(ctx context.Context, path string, data interface{}, opts ...requestOption) error {
+	cfg, err := newRequestConfig(opts...)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
+	if len(cfg.fields) > 0 {
+		path = fmt.Sprintf("%v?fields=%v", path, strings.Join(cfg.fields, ","))
+	}
+	version := cfg.apiVersion(c.version)
+	return c.getPath(ctx, fmt.Sprintf("%v/%v", version, path), data)
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// getPath is like get, but the path is not prefixed with the API version.
+func (c Client) getPath(ctx context.Context, path string, data interface{}) (err error) {
+	defer c.observe(http.MethodGet, path, c.clock().Now(), &err)
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	resp, err := c.sendPath(ctx, http.MethodGet, path, nil)
 	if err != nil {
-		return fmt.Errorf("sending request, path: %s, %w", url, err)
+		return fmt.Errorf("sending request, path: %s, %w", path, err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
-	}
-	defer resp.Body.Close()
+	defer closeBody(resp)
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readBody(resp)
 	if err != nil {
 		return fmt.Errorf("reading response body: %w", err)
 	}
-	if err = json.Unmarshal(body, data); err != nil {
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp, body)
+	}
+	if err = decode(body, data, c.strictDecoding); err != nil {
 		return fmt.Errorf("unmarshaling response: %w", err)
 	}
 	return nil
 }
 
-func (c Client) post(ctx context.Context, path string, payload interface{}) error {
-	url := fmt.Sprintf("%v/%v/%v", c.URL, c.version, path)
-	jsonInput, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshaling input: %w", err)
+// maxDrainBytes is the maximum size of the rest of a response body
+// the Client reads before closing it, so the connection can be reused.
+const maxDrainBytes = 64 << 10
+
+// closeBody drains and closes the response body, so the HTTP client
+// can reuse the connection for the next request.
+func closeBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
+
+// readBody reads the response body, up to the size
+// configured with WithMaxResponseBytes.
+func (c Client) readBody(resp *http.Response) ([]byte, error) {
+	if c.maxResponseBytes == 0 {
+		return io.ReadAll(resp.Body)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonInput))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseBytes+1))
 	if err != nil {
-		return fmt.Errorf("creating POST request: %w", err)
+		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("sending POST request %v: %w", path, err)
+	if int64(len(body)) > c.maxResponseBytes {
+		return nil, fmt.Errorf("more than %d bytes: %w", c.maxResponseBytes, ErrResponseTooLarge)
+	}
+	return body, nil
+}
+
+// APIError represents the error reported by NGINX API in the response body.
+// Callers can detect it with errors.As and branch on the Code,
+// for example "UpstreamNotFound" or "KeyvalNotFound".
+type APIError struct {
+	Status    int
+	Text      string
+	Code      string
+	RequestID string
+	Href      string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Text, e.Code)
+}
+
+// parseAPIError returns the error reported in the response body,
+// or nil when the body doesn't hold an NGINX API error.
+func parseAPIError(body []byte) *APIError {
+	var resp struct {
+		Error struct {
+			Status int    `json:"status"`
+			Text   string `json:"text"`
+			Code   string `json:"code"`
+		} `json:"error"`
+		RequestID string `json:"request_id"`
+		Href      string `json:"href"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error.Code == "" {
+		return nil
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("unexpected resp status %d", resp.StatusCode)
+	return &APIError{
+		Status:    resp.Error.Status,
+		Text:      resp.Error.Text,
+		Code:      resp.Error.Code,
+		RequestID: resp.RequestID,
+		Href:      resp.Href,
 	}
-	return nil
 }
 
-func (c Client) delete(ctx context.Context, path string, expectedStatusCode int) error {
-	path = fmt.Sprintf("%v/%v/%v/", c.URL, c.version, path)
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, path, nil)
+// checkStatus returns an error when the response status is not the expected
+// one. The error wraps the APIError reported in the response body, if any.
+func (c Client) checkStatus(resp *http.Response, expectedStatusCode int) error {
+	if resp.StatusCode == expectedStatusCode {
+		return nil
+	}
+	body, _ := c.readBody(resp)
+	return statusError(resp, body)
+}
+
+// RequestError represents a failed request to the NGINX API. It carries
+// the request method and URL, and for requests that NGINX answered,
+// the response status and the request ID, so failures can be correlated
+// with NGINX access logs. Err is the underlying error, for example
+// the APIError reported by NGINX.
+type RequestError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	RequestID  string
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	msg := fmt.Sprintf("%s %s", e.Method, e.URL)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(", status %d", e.StatusCode)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(", request id %s", e.RequestID)
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// statusError returns the RequestError of the response with an unexpected
+// status. The request ID is taken from the X-Request-ID response header
+// or the error reported in the response body.
+func statusError(resp *http.Response, body []byte) error {
+	reqErr := &RequestError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		Err:        fmt.Errorf("unexpected response status %d", resp.StatusCode),
+	}
+	if resp.Request != nil {
+		reqErr.Method = resp.Request.Method
+		reqErr.URL = resp.Request.URL.String()
+	}
+	if apiErr := parseAPIError(body); apiErr != nil {
+		reqErr.Err = apiErr
+		if reqErr.RequestID == "" {
+			reqErr.RequestID = apiErr.RequestID
+		}
+	}
+	return reqErr
+}
+
+// serverError maps the status of a failed request that changes servers
+// of an upstream to the matching sentinel error. The returned error wraps
+// both the sentinel and the original error. A 404 caused by a missing
+// upstream, rather than a missing server, is returned as it is.
+func serverError(err error) error {
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		return err
+	}
+	var sentinel error
+	switch reqErr.StatusCode {
+	case http.StatusConflict:
+		sentinel = ErrServerExists
+	case http.StatusNotFound:
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == upstreamNotFoundCode {
+			return err
+		}
+		sentinel = ErrServerNotFound
+	case http.StatusBadRequest:
+		sentinel = ErrInvalidServer
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
+
+// isPathNotFound reports whether the error is caused by requesting
+// an API path that doesn't exist, for example a stream endpoint
+// when NGINX has no stream block configured.
+func isPathNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == pathNotFoundCode
+}
+
+// decode decodes the JSON encoded body into data. Numbers decoded into
+// interface values, for example in custom stats sections, are kept as
+// json.Number instead of float64, so large counters don't lose precision.
+// Counters decoded into uint64 fields are parsed as integers and fail
+// with an error on overflow. In strict mode fields of the body that
+// data doesn't have cause an error.
+func decode(body []byte, data interface{}, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(data)
+}
+
+func (c Client) post(ctx context.Context, path string, payload interface{}) (err error) {
+	jsonInput, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("creating DELETE request: %w", err)
+		return fmt.Errorf("marshaling input: %w", err)
 	}
-	resp, err := c.HTTPClient.Do(req)
+	defer func() {
+		err = c.record(http.MethodPost, path, jsonInput, err)
+	}()
+	defer c.observe(http.MethodPost, fmt.Sprintf("%v/%v", c.version, path), c.clock().Now(), &err)
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	resp, err := c.send(ctx, http.MethodPost, path, jsonInput)
 	if err != nil {
-		return fmt.Errorf("sending DELETE request: %w", err)
+		return fmt.Errorf("sending POST request %v: %w", path, err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != expectedStatusCode {
-		return fmt.Errorf("unexpected resp status %d", resp.StatusCode)
+	defer closeBody(resp)
+	return c.checkStatus(resp, http.StatusCreated)
+}
+
+func (c Client) delete(ctx context.Context, path string, expectedStatusCode int) (err error) {
+	defer func() {
+		err = c.record(http.MethodDelete, path, nil, err)
+	}()
+	defer c.observe(http.MethodDelete, fmt.Sprintf("%v/%v", c.version, path), c.clock().Now(), &err)
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	resp, err := c.send(ctx, http.MethodDelete, path+"/", nil)
+	if err != nil {
+		return fmt.Errorf("sending DELETE request: %w", err)
 	}
-	return nil
+	defer closeBody(resp)
+	return c.checkStatus(resp, expectedStatusCode)
 }
 
-func (c Client) patch(ctx context.Context, path string, input interface{}, expectedStatusCode int) error {
-	path = fmt.Sprintf("%v/%v/%v/", c.URL, c.version, path)
+func (c Client) patch(ctx context.Context, path string, input interface{}, expectedStatusCode int) (err error) {
 	jsonInput, err := json.Marshal(input)
 	if err != nil {
 		return fmt.Errorf("marshaling input: %w", err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, path, bytes.NewBuffer(jsonInput))
+	defer func() {
+		err = c.record(http.MethodPatch, path, jsonInput, err)
+	}()
+	defer c.observe(http.MethodPatch, fmt.Sprintf("%v/%v", c.version, path), c.clock().Now(), &err)
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	resp, err := c.send(ctx, http.MethodPatch, path+"/", jsonInput)
 	if err != nil {
-		return fmt.Errorf("creating PATCH request: %w", err)
+		return fmt.Errorf("sending PATCH request: %w", err)
+	}
+	defer closeBody(resp)
+	return c.checkStatus(resp, expectedStatusCode)
+}
+
+// observe calls the function configured with WithInstrumentation
+// with the operation of the API call started at the given time.
+func (c Client) observe(method, path string, start time.Time, err *error) {
+	if c.instrument == nil {
+		return
+	}
+	path, _, _ = strings.Cut(path, "?")
+	if path == "" {
+		path = "/"
+	}
+	c.instrument(method+" "+path, c.clock().Now().Sub(start), *err)
+}
+
+// requestContext returns the context for a single API call, limited
+// by the timeout configured with WithTimeout when ctx has no deadline.
+func (c Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// send sends the request with the given method and body to the API path.
+// If the Client can't connect to its base URL, the request is sent
+// to the fallback URLs, one after another, until one of them answers.
+func (c Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	return c.sendPath(ctx, method, fmt.Sprintf("%v/%v", c.version, path), body)
+}
+
+// sendPath is like send, but the path is not prefixed with the API version.
+func (c Client) sendPath(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var err error
+	var url string
+	for _, baseURL := range append([]string{c.URL}, c.fallbackURLs...) {
+		url = fmt.Sprintf("%v/%v", baseURL, path)
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating %v request: %w", method, err)
+		}
+		req.Header.Add("Content-Type", "application/json; charset=utf-8")
+		for k, vals := range c.headers {
+			req.Header[k] = slices.Clone(vals)
+		}
+		if c.compression {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		for k, vals := range headersFromContext(ctx) {
+			for _, v := range vals {
+				req.Header.Add(k, v)
+			}
+		}
+
+		var resp *http.Response
+		start := c.clock().Now()
+		resp, err = c.HTTPClient.Do(req)
+		c.logRequest(ctx, method, url, resp, err, c.clock().Now().Sub(start))
+		if err == nil {
+			return decompress(resp)
+		}
+		if !isDialError(err) {
+			return nil, &RequestError{Method: method, URL: url, Err: err}
+		}
+	}
+	return nil, &RequestError{Method: method, URL: url, Err: err}
+}
+
+// headerContextKey is the context key of headers set with WithHeaderContext.
+type headerContextKey struct{}
+
+// WithHeaderContext returns a copy of the context that carries the HTTP header.
+// The Client attaches headers carried in the request context to every API call
+// made with the context, for example to pass tenant IDs or trace baggage.
+// Headers added to the same context accumulate.
+func WithHeaderContext(ctx context.Context, key, value string) context.Context {
+	h := headersFromContext(ctx).Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	h.Add(key, value)
+	return context.WithValue(ctx, headerContextKey{}, h)
+}
+
+func headersFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerContextKey{}).(http.Header)
+	return h
+}
+
+// decompress replaces the body of the gzip encoded response
+// with the decompressed body.
+func decompress(resp *http.Response) (*http.Response, error) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, nil
 	}
-	resp, err := c.HTTPClient.Do(req)
+	zr, err := gzip.NewReader(resp.Body)
 	if err != nil {
-		return fmt.Errorf("sending PATCH request: %w", err)
+		resp.Body.Close()
+		return nil, fmt.Errorf("decompressing response: %w", err)
+	}
+	resp.Body = gzipBody{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+// gzipBody is the decompressed body of a response,
+// that closes the original body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// logRequest logs the request with the logger configured with WithLogger.
+func (c Client) logRequest(ctx context.Context, method, url string, resp *http.Response, err error, d time.Duration) {
+	if c.logger == nil {
+		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != expectedStatusCode {
-		return fmt.Errorf("unexpected resp status %d", resp.StatusCode)
+	if err != nil {
+		c.logger.DebugContext(ctx, "nginx api request failed", "method", method, "url", url, "duration", d, "error", err)
+		return
 	}
-	return nil
+	c.logger.DebugContext(ctx, "nginx api request", "method", method, "url", url, "status", resp.StatusCode, "duration", d)
+}
+
+// isDialError reports whether the error is caused by failing
+// to connect to the server.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
 }
 
 // haveSameParameters checks if a given server has the same parameters
@@ -1431,58 +3557,151 @@ func haveSameParameters(newServer UpstreamServer, serverNGX UpstreamServer) bool
 	return cmp.Equal(newServer, serverNGX)
 }
 
+// serverPort returns the port the Client adds to servers without a port.
+func (c Client) serverPort() string {
+	if c.defaultPort == "" {
+		return defaultServerPort
+	}
+	return c.defaultPort
+}
+
 func addPortToServer(server string) string {
-	if len(strings.Split(server, ":")) == 2 {
+	return addPort(server, defaultServerPort)
+}
+
+// addPort adds the port to the server address if the address has no port.
+// IPv6 addresses are accepted with or without brackets, and are returned
+// in brackets when the port is added.
+func addPort(server, port string) string {
+	if strings.HasPrefix(server, "unix:") {
 		return server
 	}
-	if len(strings.Split(server, "]:")) == 2 {
+	if _, _, err := net.SplitHostPort(server); err == nil {
 		return server
 	}
-	if strings.HasPrefix(server, "unix:") {
+	host := strings.TrimSuffix(strings.TrimPrefix(server, "["), "]")
+	return net.JoinHostPort(host, port)
+}
+
+// normalizeServer returns the canonical form of the server address
+// used to compare servers. The port is added to addresses without a port,
+// host names are lowercased and IP addresses are in their shortest form.
+func normalizeServer(server, port string) string {
+	server = addPort(server, port)
+	host, p, err := net.SplitHostPort(server)
+	if err != nil {
 		return server
 	}
-	return fmt.Sprintf("%v:%v", server, defaultServerPort)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		host = addr.String()
+	} else {
+		host = strings.ToLower(host)
+	}
+	return net.JoinHostPort(host, p)
 }
 
-func determineServerUpdates(updatedServers []UpstreamServer, nginxServers []UpstreamServer) ([]UpstreamServer, []UpstreamServer, []UpstreamServer) {
-	var toAdd, toRemove, toUpdate []UpstreamServer
+type diffConfig struct {
+	defaultPort string
+}
 
-	for _, server := range updatedServers {
-		updateFound := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server && !haveSameParameters(server, serverNGX) {
-				server.ID = serverNGX.ID
-				updateFound = true
-				break
-			}
+// diffOption helps to configure how desired servers are compared
+// with servers configured in NGINX.
+type diffOption func(*diffConfig) error
+
+// WithDefaultPort is a func option that configures the port added
+// to desired server addresses without a port. The default port is 80.
+// The port is validated like the port configured with WithDefaultServerPort.
+func WithDefaultPort(port string) diffOption {
+	return func(cfg *diffConfig) error {
+		if err := validateDefaultPort(port); err != nil {
+			return err
 		}
-		if updateFound {
-			toUpdate = append(toUpdate, server)
+		cfg.defaultPort = port
+		return nil
+	}
+}
+
+func newDiffConfig(opts ...diffOption) (diffConfig, error) {
+	cfg := diffConfig{defaultPort: defaultServerPort}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return diffConfig{}, err
 		}
 	}
+	return cfg, nil
+}
 
-	for _, server := range updatedServers {
-		found := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server {
-				found = true
-				break
-			}
+// DetermineServerUpdates compares desired servers of an upstream with servers
+// configured in NGINX and returns servers to add, delete and update, so that
+// NGINX has the desired servers. It doesn't make any API calls.
+//
+// Desired servers without a port get the default port. Addresses are compared
+// case-insensitively, with IP addresses in their canonical form, and servers
+// to update keep the address configured in NGINX. Parameters not set
+// in desired servers are compared with their NGINX default values.
+// It returns an error only if an option is invalid.
+func DetermineServerUpdates(desired, actual []UpstreamServer, opts ...diffOption) (toAdd, toDelete, toUpdate []UpstreamServer, err error) {
+	cfg, err := newDiffConfig(opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var formattedServers []UpstreamServer
+	for _, server := range desired {
+		server.Server = addPort(server.Server, cfg.defaultPort)
+		formattedServers = append(formattedServers, server)
+	}
+	toAdd, toDelete, toUpdate = determineServerUpdates(formattedServers, actual)
+	return toAdd, toDelete, toUpdate, nil
+}
+
+// DetermineStreamServerUpdates compares desired servers of a stream upstream
+// with servers configured in NGINX and returns servers to add, delete
+// and update, so that NGINX has the desired servers.
+// It applies the same rules as DetermineServerUpdates.
+func DetermineStreamServerUpdates(desired, actual []StreamUpstreamServer, opts ...diffOption) (toAdd, toDelete, toUpdate []StreamUpstreamServer, err error) {
+	cfg, err := newDiffConfig(opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var formattedServers []StreamUpstreamServer
+	for _, server := range desired {
+		server.Server = addPort(server.Server, cfg.defaultPort)
+		formattedServers = append(formattedServers, server)
+	}
+	toAdd, toDelete, toUpdate = determineStreamUpdates(formattedServers, actual)
+	return toAdd, toDelete, toUpdate, nil
+}
+
+// determineServerUpdates matches servers by their normalized address,
+// so diffs of upstreams with many servers take linear time.
+func determineServerUpdates(updatedServers []UpstreamServer, nginxServers []UpstreamServer) ([]UpstreamServer, []UpstreamServer, []UpstreamServer) {
+	var toAdd, toRemove, toUpdate []UpstreamServer
+
+	nginxByAddress := make(map[string]UpstreamServer, len(nginxServers))
+	for _, serverNGX := range nginxServers {
+		key := normalizeServer(serverNGX.Server, defaultServerPort)
+		if _, ok := nginxByAddress[key]; !ok {
+			nginxByAddress[key] = serverNGX
 		}
-		if !found {
+	}
+	updatedAddresses := make(map[string]bool, len(updatedServers))
+	for _, server := range updatedServers {
+		key := normalizeServer(server.Server, defaultServerPort)
+		updatedAddresses[key] = true
+		serverNGX, ok := nginxByAddress[key]
+		if !ok {
 			toAdd = append(toAdd, server)
+			continue
+		}
+		server.Server = serverNGX.Server
+		if !haveSameParameters(server, serverNGX) {
+			server.ID = serverNGX.ID
+			toUpdate = append(toUpdate, server)
 		}
 	}
 
 	for _, serverNGX := range nginxServers {
-		found := false
-		for _, server := range updatedServers {
-			if serverNGX.Server == server.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if !updatedAddresses[normalizeServer(serverNGX.Server, defaultServerPort)] {
 			toRemove = append(toRemove, serverNGX)
 		}
 	}
@@ -1490,45 +3709,36 @@ func determineServerUpdates(updatedServers []UpstreamServer, nginxServers []Upst
 	return toAdd, toRemove, toUpdate
 }
 
+// determineStreamUpdates matches stream servers the same way
+// as determineServerUpdates.
 func determineStreamUpdates(updatedServers []StreamUpstreamServer, nginxServers []StreamUpstreamServer) ([]StreamUpstreamServer, []StreamUpstreamServer, []StreamUpstreamServer) {
 	var toAdd, toRemove, toUpdate []StreamUpstreamServer
 
-	for _, server := range updatedServers {
-		updateFound := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server && !haveSameParametersForStream(server, serverNGX) {
-				server.ID = serverNGX.ID
-				updateFound = true
-				break
-			}
-		}
-		if updateFound {
-			toUpdate = append(toUpdate, server)
+	nginxByAddress := make(map[string]StreamUpstreamServer, len(nginxServers))
+	for _, serverNGX := range nginxServers {
+		key := normalizeServer(serverNGX.Server, defaultServerPort)
+		if _, ok := nginxByAddress[key]; !ok {
+			nginxByAddress[key] = serverNGX
 		}
 	}
-
+	updatedAddresses := make(map[string]bool, len(updatedServers))
 	for _, server := range updatedServers {
-		found := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
+		key := normalizeServer(server.Server, defaultServerPort)
+		updatedAddresses[key] = true
+		serverNGX, ok := nginxByAddress[key]
+		if !ok {
 			toAdd = append(toAdd, server)
+			continue
+		}
+		server.Server = serverNGX.Server
+		if !haveSameParametersForStream(server, serverNGX) {
+			server.ID = serverNGX.ID
+			toUpdate = append(toUpdate, server)
 		}
 	}
 
 	for _, serverNGX := range nginxServers {
-		found := false
-		for _, server := range updatedServers {
-			if serverNGX.Server == server.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if !updatedAddresses[normalizeServer(serverNGX.Server, defaultServerPort)] {
 			toRemove = append(toRemove, serverNGX)
 		}
 	}
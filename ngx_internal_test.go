@@ -1,10 +1,15 @@
 package ngx
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -334,6 +339,24 @@ func TestCheckStreamServerUpdatesIsValidOnValidInput(t *testing.T) {
 	}
 }
 
+func TestDetermineServerUpdates_DiffsLargeUpstreamsAndKeepsServerOrder(t *testing.T) {
+	t.Parallel()
+	const n = 10000
+	var updated, nginx []UpstreamServer
+	for i := range n {
+		updated = append(updated, UpstreamServer{Server: fmt.Sprintf("10.1.%d.%d:80", i/256, i%256)})
+		nginx = append(nginx, UpstreamServer{ID: i + 1, Server: fmt.Sprintf("10.%d.%d.%d:80", 1+i%2, i/256, i%256)})
+	}
+
+	toAdd, toDelete, toUpdate := determineServerUpdates(updated, nginx)
+	if len(toAdd) != n/2 || len(toDelete) != n/2 || len(toUpdate) != 0 {
+		t.Fatalf("want %d adds, %d deletes and no updates, got %d, %d and %d", n/2, n/2, len(toAdd), len(toDelete), len(toUpdate))
+	}
+	if toAdd[0].Server != "10.1.0.1:80" || toDelete[0].Server != "10.2.0.1:80" {
+		t.Errorf("want servers in input order, got first add %v and first delete %v", toAdd[0].Server, toDelete[0].Server)
+	}
+}
+
 func TestServerAddressIsValidOnValidInputWithHostAndPort(t *testing.T) {
 	t.Parallel()
 	input := "example.com:8080"
@@ -404,6 +427,36 @@ func TestServerAddressIsValidOnValidInputWithIPV6AddressAndWithoutPort(t *testin
 	}
 }
 
+func TestServerAddressIsValidOnValidInputWithIPV6AddressWithoutBracketsAndPort(t *testing.T) {
+	t.Parallel()
+	input := "::1"
+	want := "[::1]:80"
+	got := addPortToServer(input)
+	if want != got {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestServerAddressIsValidOnValidInputWithFullIPV6AddressWithoutBracketsAndPort(t *testing.T) {
+	t.Parallel()
+	input := "2001:db8::8a2e:370:7334"
+	want := "[2001:db8::8a2e:370:7334]:80"
+	got := addPortToServer(input)
+	if want != got {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestServerAddressIsValidOnValidInputWithIPV6AddressAndCustomPort(t *testing.T) {
+	t.Parallel()
+	input := "fe80::1"
+	want := "[fe80::1]:8080"
+	got := addPort(input, "8080")
+	if want != got {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
 func TestUpstreamServersConfigIsValidOnValidInput(t *testing.T) {
 	tests := []struct {
 		server    UpstreamServer
@@ -659,3 +712,148 @@ func TestRequestGetNGINXURLIsValidOnValidFields(t *testing.T) {
 	// }
 
 }
+
+func TestGetStats_IncludesRegisteredCustomSections(t *testing.T) {
+	t.Cleanup(func() { customSections = nil })
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/8/http/custom" {
+			io.WriteString(w, `{"requests":42}`)
+			return
+		}
+		io.WriteString(w, `{}`)
+	}))
+	defer ts.Close()
+
+	type custom struct {
+		Requests uint64
+	}
+	err := RegisterSection("custom", "/http/custom", func() interface{} { return &custom{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.GetStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"custom": &custom{Requests: 42}}
+	if !cmp.Equal(want, stats.Extensions) {
+		t.Error(cmp.Diff(want, stats.Extensions))
+	}
+}
+
+func TestRegisterSection_FailsOnDuplicateName(t *testing.T) {
+	t.Cleanup(func() { customSections = nil })
+
+	factory := func() interface{} { return &map[string]interface{}{} }
+	if err := RegisterSection("custom", "http/custom", factory); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterSection("custom", "http/other", factory); err == nil {
+		t.Fatal("want error on duplicate section name, got nil")
+	}
+}
+
+func TestSectionContext_DividesRemainingDeadlineBetweenSections(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+
+	c := Client{}
+	sctx, scancel := c.sectionContext(ctx, 4)
+	defer scancel()
+
+	deadline, ok := sctx.Deadline()
+	if !ok {
+		t.Fatal("want section deadline, got none")
+	}
+	if got := time.Until(deadline); got > 2*time.Second || got < time.Second {
+		t.Errorf("want section budget of about 2s, got %v", got)
+	}
+}
+
+func TestSectionContext_CapsSectionTimeout(t *testing.T) {
+	t.Parallel()
+	c := Client{sectionTimeout: 100 * time.Millisecond}
+	sctx, cancel := c.sectionContext(context.Background(), 4)
+	defer cancel()
+
+	deadline, ok := sctx.Deadline()
+	if !ok {
+		t.Fatal("want section deadline, got none")
+	}
+	if got := time.Until(deadline); got > 100*time.Millisecond {
+		t.Errorf("want section timeout capped at 100ms, got %v", got)
+	}
+}
+
+func TestErrorRate_ReturnsRatioOf5xxResponsesBetweenSnapshots(t *testing.T) {
+	t.Parallel()
+	before := Upstream{Peers: []Peer{
+		{Responses: Responses{Responses5xx: 10, Total: 100}},
+		{Responses: Responses{Responses5xx: 0, Total: 100}},
+	}}
+	after := Upstream{Peers: []Peer{
+		{Responses: Responses{Responses5xx: 15, Total: 150}},
+		{Responses: Responses{Responses5xx: 5, Total: 150}},
+	}}
+	want := 0.1
+	got := errorRate(before, after)
+	if want != got {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestDecode_KeepsPrecisionOfLargeCounters(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"accepted":18446744073709551615,"dropped":9007199254740993}`)
+
+	var cons Connections
+	if err := decode(body, &cons, false); err != nil {
+		t.Fatal(err)
+	}
+	want := Connections{Accepted: 18446744073709551615, Dropped: 9007199254740993}
+	if !cmp.Equal(want, cons) {
+		t.Error(cmp.Diff(want, cons))
+	}
+
+	var section map[string]interface{}
+	if err := decode(body, &section, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := section["dropped"]; got != json.Number("9007199254740993") {
+		t.Errorf("want json.Number 9007199254740993, got %v", got)
+	}
+}
+
+func TestDecode_FailsOnCounterOverflow(t *testing.T) {
+	t.Parallel()
+	var cons Connections
+	if err := decode([]byte(`{"accepted":18446744073709551616}`), &cons, false); err == nil {
+		t.Fatal("want error on uint64 overflow, got nil")
+	}
+}
+
+func TestAddHTTPServer_FailsOnInvalidAddOptionWithoutCallingAPI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("want no API calls, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := func(*addConfig) error { return errors.New("invalid option") }
+	if err := c.AddHTTPServer(context.Background(), "test", UpstreamServer{Server: "10.0.0.1:80"}, invalid); err == nil {
+		t.Fatal("want error on invalid option, got nil")
+	}
+	if err := c.AddStreamServer(context.Background(), "dns", StreamUpstreamServer{Server: "10.0.0.1:53"}, invalid); err == nil {
+		t.Fatal("want error on invalid option, got nil")
+	}
+}
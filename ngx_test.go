@@ -1,16 +1,28 @@
 package ngx_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/qba73/ngx"
+	"golang.org/x/exp/slices"
 )
 
 func newTestServer(respBody string, t *testing.T) *httptest.Server {
@@ -75,11 +87,330 @@ func newNginxTestClient(baseURL string, t *testing.T) *ngx.Client {
 	return c
 }
 
+// fakeUpstream emulates dynamic configuration and peer stats
+// of the HTTP upstream "test" in the NGINX Plus API.
+type fakeUpstream struct {
+	mu        sync.Mutex
+	nextID    int
+	servers   []ngx.UpstreamServer
+	peerState string
+	mutations int
+
+	// generation is the generation of NGINX configuration and reloads
+	// is the number of next changes of the upstream that trigger
+	// a configuration reload.
+	generation int
+	reloads    int
+
+	// active are the next numbers of active connections
+	// reported for every peer. The last number is repeated.
+	active []uint64
+
+	// intruders are servers added to the upstream, as if by another
+	// client, right after the next listing of servers.
+	intruders []ngx.UpstreamServer
+
+	// methods are methods of requests that changed the upstream.
+	methods []string
+
+	// failing are servers whose peers report 10 more 5xx responses
+	// on every read of upstream stats, and failed is their count so far.
+	failing []string
+	failed  uint64
+}
+
+// newFakeUpstream returns a test server backed by fakeUpstream that reports
+// peers of all servers in the given state.
+func newFakeUpstream(peerState string, t *testing.T, servers ...ngx.UpstreamServer) (*fakeUpstream, *httptest.Server) {
+	t.Helper()
+	f := &fakeUpstream{peerState: peerState}
+	for _, s := range servers {
+		f.nextID++
+		s.ID = f.nextID
+		f.servers = append(f.servers, s)
+	}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts
+}
+
+func (f *fakeUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.Trim(r.URL.Path, "/")
+	if r.Method != http.MethodGet && f.reloads > 0 {
+		f.reloads--
+		f.generation++
+	}
+	switch {
+	case path == "8/nginx" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]int{"generation": f.generation})
+	case path == "8/http/upstreams/test" && r.Method == http.MethodGet:
+		var active uint64
+		if len(f.active) > 0 {
+			active = f.active[0]
+			if len(f.active) > 1 {
+				f.active = f.active[1:]
+			}
+		}
+		f.failed += 10
+		var peers []ngx.Peer
+		for _, s := range f.servers {
+			var responses ngx.Responses
+			if slices.Contains(f.failing, s.Server) {
+				responses = ngx.Responses{Responses5xx: f.failed, Total: f.failed}
+			}
+			peers = append(peers, ngx.Peer{
+				ID:           s.ID,
+				Server:       s.Server,
+				State:        f.peerState,
+				Active:       active,
+				HealthChecks: ngx.HealthChecks{LastPassed: f.peerState == "up"},
+				Responses:    responses,
+			})
+		}
+		json.NewEncoder(w).Encode(ngx.Upstream{Peers: peers, Zone: "test"})
+	case path == "8/http/upstreams/test/servers" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(f.servers)
+		for _, s := range f.intruders {
+			f.nextID++
+			s.ID = f.nextID
+			f.servers = append(f.servers, s)
+		}
+		f.intruders = nil
+	case path == "8/http/upstreams/test/servers" && r.Method == http.MethodPost:
+		var s ngx.UpstreamServer
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		s.ID = f.nextID
+		f.servers = append(f.servers, s)
+		f.mutations++
+		f.methods = append(f.methods, r.Method)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(s)
+	case strings.HasPrefix(path, "8/http/upstreams/test/servers/"):
+		id, err := strconv.Atoi(strings.TrimPrefix(path, "8/http/upstreams/test/servers/"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		i := slices.IndexFunc(f.servers, func(s ngx.UpstreamServer) bool { return s.ID == id })
+		if i == -1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(f.servers[i])
+		case http.MethodDelete:
+			f.servers = slices.Delete(f.servers, i, i+1)
+			f.mutations++
+			f.methods = append(f.methods, r.Method)
+			json.NewEncoder(w).Encode(f.servers)
+		case http.MethodPatch:
+			if err := json.NewDecoder(r.Body).Decode(&f.servers[i]); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.servers[i].ID = id
+			f.mutations++
+			f.methods = append(f.methods, r.Method)
+			json.NewEncoder(w).Encode(f.servers[i])
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Intrude makes the servers appear in the upstream right after
+// the next listing of servers.
+func (f *fakeUpstream) Intrude(servers ...ngx.UpstreamServer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.intruders = servers
+}
+
+// Fail makes peers of the servers report 5xx responses.
+func (f *fakeUpstream) Fail(servers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = servers
+}
+
+// Weights returns weights of servers of the upstream, by server address.
+// Servers without a weight are not included.
+func (f *fakeUpstream) Weights() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	weights := make(map[string]int)
+	for _, s := range f.servers {
+		if s.Weight != nil {
+			weights[s.Server] = *s.Weight
+		}
+	}
+	return weights
+}
+
+// Reload makes the next n changes of the upstream trigger
+// a configuration reload.
+func (f *fakeUpstream) Reload(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reloads = n
+}
+
+// SetActive sets the next numbers of active connections reported for peers.
+func (f *fakeUpstream) SetActive(active ...uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active = active
+}
+
+// Servers returns the addresses of servers currently in the upstream.
+func (f *fakeUpstream) Servers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var servers []string
+	for _, s := range f.servers {
+		servers = append(servers, s.Server)
+	}
+	return servers
+}
+
+// Methods returns methods of requests that changed the upstream, in order.
+func (f *fakeUpstream) Methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.methods)
+}
+
+// Mutations returns the number of requests that changed the upstream.
+func (f *fakeUpstream) Mutations() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mutations
+}
+
 func TestNewClient_FailsOnInvalidVersion(t *testing.T) {
 	t.Parallel()
 	_, err := ngx.NewClient("http://localhost", ngx.WithVersion(10))
+	if !errors.Is(err, ngx.ErrUnsupportedAPIVersion) {
+		t.Fatalf("want ErrUnsupportedAPIVersion on invalid version, got %v", err)
+	}
+}
+
+func TestNewClient_DetectsHighestSupportedVersion(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`[1,2,3,4,5,6,7,8,9,10]`))
+		case "/9/nginx":
+			w.Write([]byte(responseGetNGINXInfo))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithAutoVersion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatalf("want request with detected version 9, got %v", err)
+	}
+}
+
+func TestNewClient_FailsOnVersionWithAutoVersion(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`[1,2,3,4,5,6,7,8,9]`, "/", t)
+	defer ts.Close()
+
+	_, err := ngx.NewClient(ts.URL, ngx.WithVersion(7), ngx.WithAutoVersion())
+	if err == nil {
+		t.Fatal("want error on WithVersion with WithAutoVersion, got nil")
+	}
+}
+
+func TestClone_DetectsVersionOfClientWithVersion(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`[1,2,3,4,5,6,7,8,9]`, "/", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Clone(ngx.WithAutoVersion()); err != nil {
+		t.Fatalf("want clone with detected version, got %v", err)
+	}
+}
+
+func TestNewClient_FailsWhenVersionDetectionTimesOut(t *testing.T) {
+	t.Parallel()
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	_, err := ngx.NewClient(ts.URL, ngx.WithAutoVersion(), ngx.WithTimeout(50*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+}
+
+func TestNewClient_FailsWhenNoVersionSupported(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`[1,2,3]`, "/", t)
+	defer ts.Close()
+
+	_, err := ngx.NewClient(ts.URL, ngx.WithAutoVersion())
 	if err == nil {
-		t.Fatal("want error on invalid version, got nil")
+		t.Fatal("want error on no supported version, got nil")
+	}
+}
+
+func TestListSupportedAPIVersions_ReturnsVersionsListedByAPIRoot(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`[1,2,3,4,5,6,7,8]`, "/", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.ListSupportedAPIVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListEndpoints_ReturnsEndpointsOfConfiguredVersion(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`["nginx","processes","connections","slabs","http","resolvers","ssl"]`, "/8/", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.ListEndpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"nginx", "processes", "connections", "slabs", "http", "resolvers", "ssl"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
@@ -119,6 +450,274 @@ func TestGetNGINXInfo_ReturnsInfoAboutRunningNGINXInstance(t *testing.T) {
 	}
 }
 
+func TestClient_SendsHeadersCarriedInRequestContext(t *testing.T) {
+	t.Parallel()
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	ctx := ngx.WithHeaderContext(context.Background(), "X-Tenant-ID", "team-a")
+	ctx = ngx.WithHeaderContext(ctx, "Baggage", "env=prod")
+	ctx = ngx.WithHeaderContext(ctx, "Baggage", "region=eu")
+
+	if _, err := c.GetNginxInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v := got.Get("X-Tenant-ID"); v != "team-a" {
+		t.Errorf("want X-Tenant-ID header team-a, got %q", v)
+	}
+	want := []string{"env=prod", "region=eu"}
+	if !cmp.Equal(want, got.Values("Baggage")) {
+		t.Error(cmp.Diff(want, got.Values("Baggage")))
+	}
+}
+
+func TestClient_SendsConfiguredAPIKeyHeader(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithAPIKey("X-Api-Key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_FailsWhenAPICallExceedsTimeout(t *testing.T) {
+	t.Parallel()
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetNginxInfo(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClient_SendsConfiguredHeaders(t *testing.T) {
+	t.Parallel()
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	h := http.Header{}
+	h.Add("X-Tenant-ID", "team-a")
+	h.Add("X-Forwarded-For", "10.0.0.1")
+	c, err := ngx.NewClient(ts.URL, ngx.WithHeaders(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-Tenant-ID") != "team-a" || got.Get("X-Forwarded-For") != "10.0.0.1" {
+		t.Errorf("want configured headers, got %v", got)
+	}
+}
+
+func TestClient_SendsConfiguredAndContextHeadersOfConcurrentCalls(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	got := make(map[string]bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vals := r.Header.Values("X-Tenant")
+		if len(vals) != 4 || !cmp.Equal([]string{"a", "b", "c"}, vals[:3]) {
+			t.Errorf("want configured tenants and one context tenant, got %v", vals)
+		} else {
+			mu.Lock()
+			got[vals[3]] = true
+			mu.Unlock()
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithHeaders(http.Header{"X-Tenant": {"a", "b", "c"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := ngx.WithHeaderContext(context.Background(), "X-Tenant", fmt.Sprintf("tenant-%d", i))
+			if _, err := c.GetConnections(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(got) != 20 {
+		t.Errorf("want 20 distinct context tenants, got %d", len(got))
+	}
+}
+
+func TestClient_LogsRequestsAtDebugLevel(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(responseGetNGINXInfo, t)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c, err := ngx.NewClient(ts.URL, ngx.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"level=DEBUG", "method=GET", "url=" + ts.URL + "/8/nginx", "status=200", "duration="} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in log, got %q", want, got)
+		}
+	}
+}
+
+func TestClone_OverridesBaseURLAndVersionKeepingConfiguration(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/7/nginx" || r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient("http://localhost/api", ngx.WithAPIKey("X-Api-Key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone, err := c.Clone(ngx.WithBaseURL(ts.URL), ngx.WithVersion(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clone.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.URL != "http://localhost/api" {
+		t.Errorf("want original base URL unchanged, got %s", c.URL)
+	}
+	if clone.HTTPClient != c.HTTPClient {
+		t.Error("want clone to share HTTP client")
+	}
+}
+
+func TestClient_CallsInstrumentationAfterEveryAPICall(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Path == "/":
+			w.Write([]byte(`[1,2,3,4,5,6,7,8]`))
+		default:
+			w.Write([]byte(responseGetNGINXInfo))
+		}
+	}))
+	defer ts.Close()
+
+	var ops []string
+	var errs []error
+	instrument := func(op string, d time.Duration, err error) {
+		ops = append(ops, op)
+		errs = append(errs, err)
+	}
+	c, err := ngx.NewClient(ts.URL, ngx.WithInstrumentation(instrument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNGINXStatus(context.Background(), "version"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResetConnections(context.Background()); err == nil {
+		t.Fatal("want error on reset, got nil")
+	}
+	if _, err := c.ListSupportedAPIVersions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GET 8/nginx", "DELETE 8/connections", "GET /"}
+	if !cmp.Equal(want, ops) {
+		t.Error(cmp.Diff(want, ops))
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("want error only for the failed call, got %v", errs)
+	}
+}
+
+func TestClient_DecompressesGzipResponses(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(responseGetConnections))
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	h := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	c, err := ngx.NewClient(ts.URL, ngx.WithHTTPClient(h), ngx.WithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetConnections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Connections{Accepted: 9, Active: 1}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestClient_FailsOnResponseLargerThanConfiguredLimit(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(responseGetNGINXInfo, t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithMaxResponseBytes(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetNginxInfo(context.Background())
+	if !errors.Is(err, ngx.ErrResponseTooLarge) {
+		t.Fatalf("want ErrResponseTooLarge, got %v", err)
+	}
+}
+
 func TestGetNGINXStatus_ReturnsStatusInfoOnValidFields(t *testing.T) {
 	t.Parallel()
 	ts := newTestServer(responseGetNGINXStatusVersion, t)
@@ -1379,10 +1978,1718 @@ func TestGetNGINXStatus_ErrorsOnInvalidRequestParams(t *testing.T) {
 // 	// }
 // }
 
-var (
-	responseSupportedAPIVersions  = `[1,2,3,4,5,6,7,8]`
-	responseGetItems              = `["nginx","processes","connections","slabs","http","stream","resolvers","ssl"]`
-	responseGetNGINXInfo          = `{"version":"1.21.6","build":"nginx-plus-r27","address":"192.168.160.2","generation":1,"load_timestamp":"2022-09-24T11:28:33.668Z","timestamp":"2022-09-24T11:38:27.614Z","pid":8,"ppid":1}`
-	responseGetNGINXStatusVersion = `{"version":"1.21.6"}`
-	responseGetConnections        = `{"accepted":9,"dropped":0,"active":1,"idle":0}`
+func TestGetStatsStream_DeliversAllStatsSections(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/8/connections" {
+			w.Write([]byte(responseGetConnections))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+
+	got := make(map[string]interface{})
+	for section := range c.GetStatsStream(context.Background()) {
+		if section.Err != nil {
+			t.Fatalf("section %s: %v", section.Name, section.Err)
+		}
+		got[section.Name] = section.Value
+	}
+	if len(got) != 17 {
+		t.Errorf("want 17 sections, got %d", len(got))
+	}
+	want := ngx.Connections{Accepted: 9, Active: 1}
+	if !cmp.Equal(want, got[ngx.SectionConnections]) {
+		t.Error(cmp.Diff(want, got[ngx.SectionConnections]))
+	}
+}
+
+func TestUpdateHTTPServers_FailsWhenConfigurationReloadedDuringUpdate(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+	f.Reload(1)
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithReloadCheck(0))
+	if !errors.Is(err, ngx.ErrConfigReloaded) {
+		t.Fatalf("want ErrConfigReloaded, got %v", err)
+	}
+}
+
+func TestUpdateHTTPServers_RetriesUpdateWhenConfigurationReloaded(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+	f.Reload(1)
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithReloadCheck(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateHTTPServers_FailsWhenServersChangedConcurrently(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	f.Intrude(ngx.UpstreamServer{Server: "10.0.0.9:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithConcurrencyCheck())
+	if !errors.Is(err, ngx.ErrConcurrentModification) {
+		t.Fatalf("want ErrConcurrentModification, got %v", err)
+	}
+	want := []string{"10.0.0.1:80", "10.0.0.9:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateHTTPServers_AppliesChangesWhenServersDidNotChangeConcurrently(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithConcurrencyCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.2:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateHTTPServers_RefusesToRemoveAllServersWithMinServers(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", nil, ngx.WithMinServers(1))
+	if !errors.Is(err, ngx.ErrTooFewServers) {
+		t.Fatalf("want ErrTooFewServers, got %v", err)
+	}
+	want := []string{"10.0.0.1:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateStreamServers_FailsWithTooFewServersForMinServers(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://127.0.0.1:0", t)
+	servers := []ngx.StreamUpstreamServer{{Server: "10.0.0.1:53"}}
+	_, _, _, err := c.UpdateStreamServers(context.Background(), "test", servers, ngx.WithMinServers(2))
+	if !errors.Is(err, ngx.ErrTooFewServers) {
+		t.Fatalf("want ErrTooFewServers, got %v", err)
+	}
+}
+
+func TestWithMinServers_FailsOnNonPositiveNumber(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://127.0.0.1:0", t)
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", nil, ngx.WithMinServers(0))
+	if err == nil {
+		t.Fatal("want error on zero min servers, got nil")
+	}
+}
+
+func TestUpdateHTTPServers_AddsUpdatesAndDeletesServersInOrderWithZeroDowntime(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.UpstreamServer{Server: "10.0.0.2:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	weight := 5
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80", Weight: &weight}, {Server: "10.0.0.3:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithZeroDowntime(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{http.MethodPost, http.MethodPatch, http.MethodDelete}
+	if got := f.Methods(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateHTTPServers_KeepsOldServersWhenAddedServersAreUnhealthyWithZeroDowntime(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("unhealthy", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithZeroDowntime(50*time.Millisecond))
+	if !errors.Is(err, ngx.ErrServerNotHealthy) {
+		t.Fatalf("want ErrServerNotHealthy, got %v", err)
+	}
+	want := []string{"10.0.0.1:80", "10.0.0.2:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUpdateStreamServers_WaitsForAddedServersUsingStatsOfTheUpstreamWithZeroDowntime(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.Trim(r.URL.Path, "/"); {
+		case path == "8/stream/upstreams/dns/servers" && r.Method == http.MethodGet:
+			w.Write([]byte(`[]`))
+		case path == "8/stream/upstreams/dns/servers" && r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1,"server":"10.0.0.1:53"}`))
+		case path == "8/stream/upstreams/dns" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"peers":[{"id":1,"server":"10.0.0.1:53","state":"up","health_checks":{"last_passed":true}}],"zone":"dns"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.StreamUpstreamServer{{Server: "10.0.0.1:53"}}
+	if _, _, _, err := c.UpdateStreamServers(context.Background(), "dns", servers, ngx.WithZeroDowntime(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateHTTPServers_ReturnsChangesWithoutMakingThemWithDryRun(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	toAdd, toDelete, toUpdate, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}; !cmp.Equal(want, toAdd) {
+		t.Error(cmp.Diff(want, toAdd))
+	}
+	if want := []ngx.UpstreamServer{{ID: 1, Server: "10.0.0.1:80"}}; !cmp.Equal(want, toDelete) {
+		t.Error(cmp.Diff(want, toDelete))
+	}
+	if len(toUpdate) != 0 {
+		t.Errorf("want no updates, got %v", toUpdate)
+	}
+	if got := f.Mutations(); got != 0 {
+		t.Errorf("want no changes in NGINX, got %d", got)
+	}
+}
+
+func TestUpdateStreamServers_DoesNotChangeNGINXWithDryRun(t *testing.T) {
+	t.Parallel()
+	var mutations int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			atomic.AddInt32(&mutations, 1)
+		}
+		w.Write([]byte(`[{"id":1,"server":"10.0.0.1:53"}]`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.StreamUpstreamServer{{Server: "10.0.0.2:53"}}
+	toAdd, toDelete, _, err := c.UpdateStreamServers(context.Background(), "test", servers, ngx.WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toAdd) != 1 || len(toDelete) != 1 {
+		t.Errorf("want 1 add and 1 delete, got add %v, delete %v", toAdd, toDelete)
+	}
+	if got := atomic.LoadInt32(&mutations); got != 0 {
+		t.Errorf("want no changes in NGINX, got %d", got)
+	}
+}
+
+func TestUpdateHTTPServers_ChangesServersConcurrentlyWithParallelism(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight, posts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[]`))
+			return
+		}
+		atomic.AddInt32(&posts, 1)
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	var servers []ngx.UpstreamServer
+	for i := range 12 {
+		servers = append(servers, ngx.UpstreamServer{Server: fmt.Sprintf("10.0.0.%d:80", i+1)})
+	}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithParallelism(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&posts); got != 12 {
+		t.Errorf("want 12 servers added, got %d", got)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got < 2 || got > 4 {
+		t.Errorf("want 2 to 4 concurrent requests, got %d", got)
+	}
+}
+
+func TestUpdateHTTPServers_JoinsErrorsOfFailedChangesWithParallelism(t *testing.T) {
+	t.Parallel()
+	var posts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[]`))
+			return
+		}
+		atomic.AddInt32(&posts, 1)
+		var s ngx.UpstreamServer
+		json.NewDecoder(r.Body).Decode(&s)
+		if strings.HasPrefix(s.Server, "bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "bad1:80"}, {Server: "10.0.0.1:80"}, {Server: "bad2:80"}}
+	_, _, _, err := c.UpdateHTTPServers(context.Background(), "test", servers, ngx.WithParallelism(2))
+	if !errors.Is(err, ngx.ErrInvalidServer) {
+		t.Fatalf("want ErrInvalidServer, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "bad1:80") || !strings.Contains(err.Error(), "bad2:80") {
+		t.Errorf("want errors of both failed servers, got %v", err)
+	}
+	if got := atomic.LoadInt32(&posts); got != 3 {
+		t.Errorf("want 3 add requests, got %d", got)
+	}
+}
+
+func TestStatsAllPeers_YieldsPeersOfAllUpstreams(t *testing.T) {
+	t.Parallel()
+	stats := ngx.Stats{
+		Upstreams: ngx.Upstreams{
+			"one": {Peers: []ngx.Peer{{ID: 1}, {ID: 2}}},
+			"two": {Peers: []ngx.Peer{{ID: 3}}},
+		},
+	}
+	got := make(map[int]string)
+	for name, peer := range stats.AllPeers() {
+		got[peer.ID] = name
+	}
+	want := map[int]string{1: "one", 2: "one", 3: "two"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestStatsAllServerZones_StopsWhenLoopBreaks(t *testing.T) {
+	t.Parallel()
+	stats := ngx.Stats{
+		ServerZones: ngx.ServerZones{"one": {}, "two": {}, "three": {}},
+	}
+	var visited int
+	for range stats.AllServerZones() {
+		visited++
+		break
+	}
+	if visited != 1 {
+		t.Errorf("want 1 visited zone, got %d", visited)
+	}
+}
+
+func TestDetermineServerUpdates_AddsDefaultPortBeforeComparingServers(t *testing.T) {
+	t.Parallel()
+	desired := []ngx.UpstreamServer{{Server: "10.0.0.1"}, {Server: "10.0.0.2"}}
+	actual := []ngx.UpstreamServer{{ID: 1, Server: "10.0.0.1:80"}, {ID: 2, Server: "10.0.0.3:80"}}
+
+	toAdd, toDelete, toUpdate, err := ngx.DetermineServerUpdates(desired, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantAdd := []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}
+	if !cmp.Equal(wantAdd, toAdd) {
+		t.Error(cmp.Diff(wantAdd, toAdd))
+	}
+	wantDelete := []ngx.UpstreamServer{{ID: 2, Server: "10.0.0.3:80"}}
+	if !cmp.Equal(wantDelete, toDelete) {
+		t.Error(cmp.Diff(wantDelete, toDelete))
+	}
+	if len(toUpdate) != 0 {
+		t.Errorf("want no updates, got %v", toUpdate)
+	}
+}
+
+func TestDetermineStreamServerUpdates_UsesConfiguredDefaultPort(t *testing.T) {
+	t.Parallel()
+	desired := []ngx.StreamUpstreamServer{{Server: "10.0.0.1"}}
+	actual := []ngx.StreamUpstreamServer{{ID: 1, Server: "10.0.0.1:5353"}}
+
+	toAdd, toDelete, toUpdate, err := ngx.DetermineStreamServerUpdates(desired, actual, ngx.WithDefaultPort("5353"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toAdd) != 0 || len(toDelete) != 0 || len(toUpdate) != 0 {
+		t.Errorf("want no changes, got add %v, delete %v, update %v", toAdd, toDelete, toUpdate)
+	}
+}
+
+func TestDetermineServerUpdates_FailsOnInvalidDefaultPort(t *testing.T) {
+	t.Parallel()
+	desired := []ngx.UpstreamServer{{Server: "10.0.0.1"}}
+
+	for _, port := range []string{"", "http", "0", "65536"} {
+		if _, _, _, err := ngx.DetermineServerUpdates(desired, nil, ngx.WithDefaultPort(port)); err == nil {
+			t.Errorf("want error on default port %q, got nil", port)
+		}
+	}
+}
+
+func TestDetermineServerUpdates_NormalizesAddressesBeforeComparingServers(t *testing.T) {
+	t.Parallel()
+	desired := []ngx.UpstreamServer{{Server: "Example.com"}, {Server: "::0001"}, {Server: "10.0.0.1:8080"}}
+	actual := []ngx.UpstreamServer{
+		{ID: 1, Server: "example.com:80"},
+		{ID: 2, Server: "[::1]:80"},
+		{ID: 3, Server: "10.0.0.1:8080"},
+	}
+
+	toAdd, toDelete, toUpdate, err := ngx.DetermineServerUpdates(desired, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toAdd) != 0 || len(toDelete) != 0 || len(toUpdate) != 0 {
+		t.Errorf("want no changes, got add %v, delete %v, update %v", toAdd, toDelete, toUpdate)
+	}
+}
+
+func TestDetermineServerUpdates_UpdatesServerUsingAddressConfiguredInNGINX(t *testing.T) {
+	t.Parallel()
+	weight := 5
+	desired := []ngx.UpstreamServer{{Server: "EXAMPLE.com", Weight: &weight}}
+	actual := []ngx.UpstreamServer{{ID: 1, Server: "example.com:80"}}
+
+	toAdd, toDelete, toUpdate, err := ngx.DetermineServerUpdates(desired, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toAdd) != 0 || len(toDelete) != 0 {
+		t.Errorf("want no adds and deletes, got add %v, delete %v", toAdd, toDelete)
+	}
+	want := []ngx.UpstreamServer{{ID: 1, Server: "example.com:80", Weight: &weight}}
+	if !cmp.Equal(want, toUpdate) {
+		t.Error(cmp.Diff(want, toUpdate))
+	}
+}
+
+func TestDetermineStreamServerUpdates_NormalizesAddressesBeforeComparingServers(t *testing.T) {
+	t.Parallel()
+	desired := []ngx.StreamUpstreamServer{{Server: "DNS.Example.com"}}
+	actual := []ngx.StreamUpstreamServer{{ID: 1, Server: "dns.example.com:53"}}
+
+	toAdd, toDelete, toUpdate, err := ngx.DetermineStreamServerUpdates(desired, actual, ngx.WithDefaultPort("53"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toAdd) != 0 || len(toDelete) != 0 || len(toUpdate) != 0 {
+		t.Errorf("want no changes, got add %v, delete %v, update %v", toAdd, toDelete, toUpdate)
+	}
+}
+
+func TestDiffKeyValPairs_ReturnsPairsToAddModifyAndDelete(t *testing.T) {
+	t.Parallel()
+	desired := ngx.KeyValPairs{"a": "1", "b": "2", "c": "3"}
+	actual := ngx.KeyValPairs{"b": "2", "c": "30", "d": "4"}
+
+	toAdd, toModify, toDelete := ngx.DiffKeyValPairs(desired, actual)
+
+	if want := (ngx.KeyValPairs{"a": "1"}); !cmp.Equal(want, toAdd) {
+		t.Error(cmp.Diff(want, toAdd))
+	}
+	if want := (ngx.KeyValPairs{"c": "3"}); !cmp.Equal(want, toModify) {
+		t.Error(cmp.Diff(want, toModify))
+	}
+	if want := (ngx.KeyValPairs{"d": "4"}); !cmp.Equal(want, toDelete) {
+		t.Error(cmp.Diff(want, toDelete))
+	}
+}
+
+func TestGetStreamZoneSyncStatus_RequestsOnlyStatusDocument(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseGetStreamZoneSyncStatus, "/8/stream/zone_sync/status", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamZoneSyncStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StreamZoneSyncStatus{BytesIn: 1024, MsgsIn: 8, MsgsOut: 4, BytesOut: 512, NodesOnline: 2}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamZoneSyncZones_ReturnsZonesByName(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseGetStreamZoneSyncZones, "/8/stream/zone_sync/zones", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamZoneSyncZones(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ngx.SyncZone{"zone_test_sync": {RecordsPending: 1, RecordsTotal: 10}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetWorkers_ReturnsStatsOfWorkerProcesses(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseGetWorkers, "/9/workers", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetWorkers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Workers{
+		{
+			ID:          0,
+			ProcessID:   3211,
+			Connections: ngx.Connections{Accepted: 10, Active: 2, Idle: 1},
+			HTTP:        ngx.WorkerHTTP{HTTPRequests: ngx.HTTPRequests{Total: 52, Current: 1}},
+		},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetWorkers_FailsOnAPIVersionWithoutWorkers(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if _, err := c.GetWorkers(context.Background()); err == nil {
+		t.Fatal("want error on API version 8, got nil")
+	}
+}
+
+func TestGetStats_ReturnsEmptyStreamSectionsWithoutStreamConfiguration(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/8/stream/"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(responsePathNotFound))
+		case r.URL.Path == "/8/nginx":
+			w.Write([]byte(responseGetNGINXInfo))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	stats, err := c.GetStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.StreamServerZones) != 0 || len(stats.StreamUpstreams) != 0 || len(stats.StreamLimitConnections) != 0 {
+		t.Errorf("want empty stream sections, got %+v", stats)
+	}
+}
+
+func TestGetStats_FailsWhenHTTPSectionIsNotFound(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/8/http/location_zones":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(responsePathNotFound))
+		case "/8/nginx":
+			w.Write([]byte(responseGetNGINXInfo))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetStats(context.Background()); err == nil {
+		t.Fatal("want error on missing location zones, got nil")
+	}
+}
+
+func TestGetStreamUpstreams_FailsWithoutStreamConfiguration(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(responsePathNotFound))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetStreamUpstreams(context.Background()); err == nil {
+		t.Fatal("want error on missing stream configuration, got nil")
+	}
+}
+
+func TestGetLocationZones_FailsOnAPIVersionWithoutLocationZones(t *testing.T) {
+	t.Parallel()
+	c, err := ngx.NewClient("http://localhost/api", ngx.WithVersion(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetLocationZones(context.Background())
+	if !errors.Is(err, ngx.ErrUnsupportedAPIVersion) {
+		t.Fatalf("want ErrUnsupportedAPIVersion, got %v", err)
+	}
+}
+
+func TestGetStats_ReturnsEmptySectionsUnsupportedByAPIVersion(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/4/nginx" {
+			w.Write([]byte(responseGetNGINXInfo))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.GetStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.LocationZones) != 0 || len(stats.Resolvers) != 0 || len(stats.HTTPLimitRequests) != 0 {
+		t.Errorf("want empty sections unsupported by version 4, got %+v", stats)
+	}
+}
+
+func TestGetStats_IncludesWorkersForAPIVersion9(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/9/workers":
+			w.Write([]byte(responseGetWorkers))
+		case "/9/nginx":
+			w.Write([]byte(responseGetNGINXInfo))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.GetStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Workers{
+		{
+			ProcessID:   3211,
+			Connections: ngx.Connections{Accepted: 10, Active: 2, Idle: 1},
+			HTTP:        ngx.WorkerHTTP{HTTPRequests: ngx.HTTPRequests{Total: 52, Current: 1}},
+		},
+	}
+	if !cmp.Equal(want, stats.Workers) {
+		t.Error(cmp.Diff(want, stats.Workers))
+	}
+}
+
+func TestGetStatsStream_IncludesWorkersForAPIVersion9(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/9/workers" {
+			w.Write([]byte(responseGetWorkers))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]interface{})
+	for section := range c.GetStatsStream(context.Background()) {
+		if section.Err != nil {
+			t.Fatalf("section %s: %v", section.Name, section.Err)
+		}
+		got[section.Name] = section.Value
+	}
+	if len(got) != 18 {
+		t.Errorf("want 18 sections, got %d", len(got))
+	}
+	if workers, ok := got[ngx.SectionWorkers].(ngx.Workers); !ok || len(workers) != 1 {
+		t.Errorf("want stats of 1 worker, got %v", got[ngx.SectionWorkers])
+	}
+}
+
+func TestGetLicense_ReturnsLicenseExpiry(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseGetLicense, "/9/license", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetLicense(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.License{
+		ActiveTill: time.Date(2025, time.June, 13, 0, 0, 0, 0, time.UTC),
+		Reporting:  ngx.LicenseReporting{Healthy: true, Grace: 180 * 24 * time.Hour},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestWaitForAPI_ReturnsWhenAPIStartsAnswering(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.WaitForAPI(context.Background(), 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("want 3 calls, got %d", got)
+	}
+}
+
+func TestWaitForAPI_FailsWhenAPIDoesNotAnswerInTime(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.WaitForAPI(context.Background(), 150*time.Millisecond); err == nil {
+		t.Fatal("want error on timeout, got nil")
+	}
+}
+
+func TestAddHTTPServers_AddsMissingServersReadingServersOnce(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1"}, {Server: "10.0.0.2:80"}, {Server: "10.0.0.3:80"}}
+	results, err := c.AddHTTPServers(context.Background(), "test", servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ngx.AddResult{
+		{Server: "10.0.0.1"},
+		{Server: "10.0.0.2:80", Added: true},
+		{Server: "10.0.0.3:80", Added: true},
+	}
+	if !cmp.Equal(want, results) {
+		t.Error(cmp.Diff(want, results))
+	}
+	if got := f.Methods(); !cmp.Equal([]string{http.MethodPost, http.MethodPost}, got) {
+		t.Errorf("want 2 adds, got %v", got)
+	}
+}
+
+func TestAddHTTPServers_ReportsFailedServersAndAddsTheRest(t *testing.T) {
+	t.Parallel()
+	var gets int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+			w.Write([]byte(`[]`))
+			return
+		}
+		var s ngx.UpstreamServer
+		json.NewDecoder(r.Body).Decode(&s)
+		if s.Server == "bad:80" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "bad:80"}, {Server: "10.0.0.1:80"}}
+	results, err := c.AddHTTPServers(context.Background(), "test", servers)
+	if !errors.Is(err, ngx.ErrInvalidServer) {
+		t.Fatalf("want ErrInvalidServer, got %v", err)
+	}
+	if len(results) != 2 || !errors.Is(results[0].Err, ngx.ErrInvalidServer) || !results[1].Added {
+		t.Errorf("want first server failed and second added, got %+v", results)
+	}
+	if got := atomic.LoadInt32(&gets); got != 1 {
+		t.Errorf("want servers read once, got %d", got)
+	}
+}
+
+func TestAddHTTPServers_FailsOnUnsupportedOption(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	_, err := c.AddHTTPServers(context.Background(), "test", []ngx.UpstreamServer{{Server: "10.0.0.1:80"}}, ngx.WithDryRun())
+	if err == nil {
+		t.Fatal("want error on unsupported option, got nil")
+	}
+	if f.Mutations() != 0 {
+		t.Error("want upstream untouched")
+	}
+}
+
+func TestAddHTTPServers_AddsServersConcurrentlyWithParallelism(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}, {Server: "10.0.0.3:80"}}
+	results, err := c.AddHTTPServers(context.Background(), "test", servers, ngx.WithParallelism(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if !r.Added {
+			t.Errorf("want %v added", r.Server)
+		}
+	}
+	if got := f.Mutations(); got != 3 {
+		t.Errorf("want 3 adds, got %d", got)
+	}
+}
+
+func TestAddStreamServers_SkipsServersAlreadyInUpstream(t *testing.T) {
+	t.Parallel()
+	var posts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[{"id":1,"server":"10.0.0.1:53"}]`))
+			return
+		}
+		atomic.AddInt32(&posts, 1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	servers := []ngx.StreamUpstreamServer{{Server: "10.0.0.1:53"}, {Server: "10.0.0.2:53"}}
+	results, err := c.AddStreamServers(context.Background(), "test", servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ngx.AddResult{{Server: "10.0.0.1:53"}, {Server: "10.0.0.2:53", Added: true}}
+	if !cmp.Equal(want, results) {
+		t.Error(cmp.Diff(want, results))
+	}
+	if got := atomic.LoadInt32(&posts); got != 1 {
+		t.Errorf("want 1 add, got %d", got)
+	}
+}
+
+func TestGetHTTPServerByName_ReturnsServerWithIDAndParameters(t *testing.T) {
+	t.Parallel()
+	weight := 3
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.UpstreamServer{Server: "10.0.0.2:80", Weight: &weight})
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetHTTPServerByName(context.Background(), "test", "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.UpstreamServer{ID: 2, Server: "10.0.0.2:80", Weight: &weight}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetHTTPServerByName_FailsWithErrServerNotFoundOnUnknownServer(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	_, err := c.GetHTTPServerByName(context.Background(), "test", "10.0.0.9:80")
+	if !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestGetStreamServerByName_ReturnsServer(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":4,"server":"dns.example.com:53"}]`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamServerByName(context.Background(), "test", "DNS.example.com:53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StreamUpstreamServer{ID: 4, Server: "dns.example.com:53"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestAddHTTPServerAndWait_ReturnsWhenServerPassesHealthChecks(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("up", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServerAndWait(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers, err := c.GetHTTPServers(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Down != nil && *servers[0].Down {
+		t.Errorf("want server up, got %+v", servers)
+	}
+}
+
+func TestAddHTTPServerAndWait_FailsWhenServerIsUnhealthy(t *testing.T) {
+	t.Parallel()
+	_, ts := newFakeUpstream("unhealthy", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServerAndWait(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, 50*time.Millisecond)
+	if !errors.Is(err, ngx.ErrServerNotHealthy) {
+		t.Fatalf("want ErrServerNotHealthy, got %v", err)
+	}
+	servers, err := c.GetHTTPServers(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Down == nil || !*servers[0].Down {
+		t.Errorf("want server added as down, got %+v", servers)
+	}
+}
+
+func TestAddHTTPServerAndWait_FailsOnNonPositiveTimeoutWithoutAddingServer(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.AddHTTPServerAndWait(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, 0); err == nil {
+		t.Fatal("want error on zero timeout, got nil")
+	}
+	if got := f.Servers(); len(got) != 0 {
+		t.Errorf("want no servers added, got %v", got)
+	}
+}
+
+func TestAddServer_SkipsListingServersWithoutExistenceCheck(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("want only POST requests, got %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1,"server":"10.0.0.1:80"}`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.WithoutExistenceCheck()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddStreamServer(context.Background(), "dns", ngx.StreamUpstreamServer{Server: "10.0.0.1:53"}, ngx.WithoutExistenceCheck()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddHTTPServer_ReportsErrServerExistsWhenNGINXRespondsWithConflict(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusConflict, "UpstreamServerExists", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.2:80"})
+	if !errors.Is(err, ngx.ErrServerExists) {
+		t.Fatalf("want ErrServerExists, got %v", err)
+	}
+	var reqErr *ngx.RequestError
+	if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusConflict {
+		t.Errorf("want RequestError with status 409, got %v", err)
+	}
+}
+
+func TestAddHTTPServer_ReportsErrServerExistsWhenServerIsInUpstream(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusConflict, "UpstreamServerExists", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"})
+	if !errors.Is(err, ngx.ErrServerExists) {
+		t.Fatalf("want ErrServerExists, got %v", err)
+	}
+}
+
+func TestAddHTTPServer_ReportsErrInvalidServerWhenNGINXRespondsWithBadRequest(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusBadRequest, "UpstreamBadAddress", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "bogus"})
+	if !errors.Is(err, ngx.ErrInvalidServer) {
+		t.Fatalf("want ErrInvalidServer, got %v", err)
+	}
+}
+
+func TestAddHTTPServer_SkipsExistenceCheckWithoutExistenceCheckOption(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.WithoutExistenceCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"POST /8/http/upstreams/test/servers/"}
+	if !cmp.Equal(want, requests) {
+		t.Error(cmp.Diff(want, requests))
+	}
+}
+
+func TestAddHTTPServer_ReportsErrServerExistsFromNGINXWithoutExistenceCheck(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusConflict, "UpstreamServerExists", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddHTTPServer(context.Background(), "test", ngx.UpstreamServer{Server: "10.0.0.1:80"}, ngx.WithoutExistenceCheck())
+	if !errors.Is(err, ngx.ErrServerExists) {
+		t.Fatalf("want ErrServerExists, got %v", err)
+	}
+}
+
+func TestDeleteHTTPServerByID_DeletesServerWithoutListingServers(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.DeleteHTTPServerByID(context.Background(), "test", 7); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"DELETE /8/http/upstreams/test/servers/7/"}
+	if !cmp.Equal(want, requests) {
+		t.Error(cmp.Diff(want, requests))
+	}
+}
+
+func TestDeleteStreamServerByID_ReportsErrServerNotFoundWhenNGINXRespondsWithNotFound(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusNotFound, "UpstreamServerNotFound", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.DeleteStreamServerByID(context.Background(), "test", 7)
+	if !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestDeleteHTTPServer_ReportsErrServerNotFoundWhenNGINXRespondsWithNotFound(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusNotFound, "UpstreamServerNotFound", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.DeleteHTTPServer(context.Background(), "test", "10.0.0.1:80")
+	if !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestDeleteStreamServer_ReportsErrServerNotFoundWhenServerIsNotInUpstream(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusNotFound, "UpstreamServerNotFound", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.DeleteStreamServer(context.Background(), "test", "10.0.0.2:80")
+	if !errors.Is(err, ngx.ErrServerNotFound) {
+		t.Fatalf("want ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestUpdateHTTPServer_DoesNotReportErrServerNotFoundWhenUpstreamIsMissing(t *testing.T) {
+	t.Parallel()
+	ts := newServerMutationErrorTestServer(http.StatusNotFound, "UpstreamNotFound", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.UpdateHTTPServer(context.Background(), "test", ngx.UpstreamServer{ID: 1, Server: "10.0.0.1:80"})
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if errors.Is(err, ngx.ErrServerNotFound) {
+		t.Errorf("want error not to be ErrServerNotFound, got %v", err)
+	}
+}
+
+// newServerMutationErrorTestServer returns a test server that lists
+// the 10.0.0.1:80 server in the test upstream and fails all requests
+// changing servers with the given status and NGINX API error code.
+func newServerMutationErrorTestServer(status int, code string, t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[{"id":1,"server":"10.0.0.1:80"}]`))
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"error":{"status":%d,"text":"request failed","code":%q},"request_id":"abc"}`, status, code)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestGetConnections_FailsOnUnknownFieldsWithStrictDecoding(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"accepted":4968119,"dropped":0,"active":5,"idle":117,"rejected":3}`, "/8/connections", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithHTTPClient(ts.Client()), ngx.WithStrictDecoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetConnections(context.Background())
+	if err == nil {
+		t.Fatal("want error on unknown field, got nil")
+	}
+	if !strings.Contains(err.Error(), `"rejected"`) {
+		t.Errorf("want error to name the unknown field, got %v", err)
+	}
+}
+
+func TestGetConnections_IgnoresUnknownFieldsByDefault(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"accepted":4968119,"dropped":0,"active":5,"idle":117,"rejected":3}`, "/8/connections", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetConnections(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_FallsBackToNextURLWhenConnectionFails(t *testing.T) {
+	t.Parallel()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	ts := newTestServerWithPathValidator(responseGetConnections, "/8/connections", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(unreachable.URL, ngx.WithFallbackURLs(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetConnections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Connections{Accepted: 9, Active: 1}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetServerZone_ReturnsStatsOfSingleZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"processing":1,"requests":42,"discarded":2}`, "/8/http/server_zones/zone_one", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetServerZone(context.Background(), "zone_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.ServerZone{Processing: 1, Requests: 42, Discarded: 2}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamServerZone_ReturnsStatsOfSingleStreamServerZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"processing":0,"connections":12,"discarded":1}`, "/8/stream/server_zones/dns_tcp", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamServerZone(context.Background(), "dns_tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StreamServerZone{Connections: 12, Discarded: 1}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetUpstream_ReturnsStatsOfSingleUpstream(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"peers":[{"id":0,"server":"10.0.0.1:80","state":"up"}],"zombies":1,"zone":"backend"}`, "/8/http/upstreams/backend", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetUpstream(context.Background(), "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Upstream{Peers: []ngx.Peer{{ID: 0, Server: "10.0.0.1:80", State: "up"}}, Zombies: 1, Zone: "backend"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamUpstream_ReturnsStatsOfSingleStreamUpstream(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"peers":[{"id":0,"server":"10.0.0.1:53","state":"up"}],"zombies":0,"zone":"dns_backend"}`, "/8/stream/upstreams/dns_backend", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamUpstream(context.Background(), "dns_backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StreamUpstream{Peers: []ngx.StreamPeer{{ID: 0, Server: "10.0.0.1:53", State: "up"}}, Zone: "dns_backend"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetCache_ReturnsStatsOfSingleCache(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"size":1024,"max_size":4096,"cold":false}`, "/8/http/caches/http_cache", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetCache(context.Background(), "http_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.HTTPCache{Size: 1024, MaxSize: 4096}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetSlab_ReturnsStatsOfSingleSlab(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"pages":{"used":4,"free":12}}`, "/8/slabs/zone_one", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetSlab(context.Background(), "zone_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Slab{Pages: ngx.Pages{Used: 4, Free: 12}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetResolver_ReturnsStatsOfSingleResolver(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"requests":{"name":5,"srv":1},"responses":{"noerror":5,"nxdomain":1}}`, "/8/resolvers/resolver_one", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetResolver(context.Background(), "resolver_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.Resolver{Requests: ngx.ResolverRequests{Name: 5, Srv: 1}, Responses: ngx.ResolverResponses{Noerror: 5, Nxdomain: 1}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetLocationZone_ReturnsStatsOfSingleLocationZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"requests":7,"discarded":1,"received":340,"sent":900}`, "/8/http/location_zones/location_one", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetLocationZone(context.Background(), "location_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.LocationZone{Requests: 7, Discarded: 1, Received: 340, Sent: 900}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetHTTPServerByID_ReturnsServerWithGivenID(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"id":3,"server":"10.0.0.3:80","weight":2}`, "/8/http/upstreams/backend/servers/3", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetHTTPServerByID(context.Background(), "backend", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weight := 2
+	want := ngx.UpstreamServer{ID: 3, Server: "10.0.0.3:80", Weight: &weight}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamServerByID_ReturnsServerWithGivenID(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"id":1,"server":"10.0.0.1:53"}`, "/8/stream/upstreams/dns_backend/servers/1", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamServerByID(context.Background(), "dns_backend", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StreamUpstreamServer{ID: 1, Server: "10.0.0.1:53"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetHTTPLimitReq_ReturnsStatsOfSingleLimitRequestsZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"passed":15,"delayed":4,"rejected":2,"delayed_dry_run":0,"rejected_dry_run":0}`, "/8/http/limit_reqs/limit_one", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetHTTPLimitReq(context.Background(), "limit_one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.HTTPLimitRequest{Passed: 15, Delayed: 4, Rejected: 2}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetHTTPLimitConn_ReturnsStatsOfSingleLimitConnectionsZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"passed":15,"rejected":1,"rejected_dry_run":0}`, "/8/http/limit_conns/addr", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetHTTPLimitConn(context.Background(), "addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.LimitConnection{Passed: 15, Rejected: 1}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamLimitConn_ReturnsStatsOfSingleStreamLimitConnectionsZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"passed":8,"rejected":0,"rejected_dry_run":2}`, "/8/stream/limit_conns/addr", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamLimitConn(context.Background(), "addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.LimitConnection{Passed: 8, RejectedDryRun: 2}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetServerZones_RequestsOnlyGivenFields(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"zone_one":{"requests":42}}`, "/8/http/server_zones?fields=requests,responses", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetServerZones(context.Background(), ngx.WithFields("requests", "responses"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.ServerZones{"zone_one": {Requests: 42}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetUpstream_RequestsOnlyGivenFields(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"zombies":1}`, "/8/http/upstreams/backend?fields=zombies", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetUpstream(context.Background(), "backend", ngx.WithFields("zombies")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetKeyValPair_RequestsSingleKey(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"user 1":"admin"}`, "/8/http/keyvals/users?key=user+1", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetKeyValPair(context.Background(), "users", "user 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "admin" {
+		t.Errorf("want value admin, got %q", got)
+	}
+}
+
+func TestGetStreamKeyValPair_FailsWhenKeyNotFound(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{}`, "/8/stream/keyvals/users?key=user1", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetStreamKeyValPair(context.Background(), "users", "user1"); err == nil {
+		t.Fatal("want error on missing key, got nil")
+	}
+}
+
+func TestGetStreamZoneSyncZone_ReturnsStatusOfSingleZone(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"records_pending":2,"records_total":20}`, "/8/stream/zone_sync/zones/zone_test_sync", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamZoneSyncZone(context.Background(), "zone_test_sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.SyncZone{RecordsPending: 2, RecordsTotal: 20}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetSSL_ReturnsExtendedStats(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseGetSSL, "/8/ssl", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetSSL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.SSL{
+		Handshakes:       79,
+		HandshakesFailed: 21,
+		SessionReuses:    15,
+		NoCommonProtocol: 4,
+		NoCommonCipher:   2,
+		HandshakeTimeout: 1,
+		PeerRejectedCert: 3,
+		VerifyFailures:   ngx.VerifyFailures{NoCert: 1, ExpiredCert: 2, HostnameMismatch: 5},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetSSL_ReturnsBasicStatsReportedByAPIVersion7(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"handshakes":79,"handshakes_failed":21,"session_reuses":15}`, "/7/ssl", t)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithVersion(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetSSL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.SSL{Handshakes: 79, HandshakesFailed: 21, SessionReuses: 15}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStreamUpstreams_ReturnsVerifyFailuresOfPeers(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{"dns_backend":{"peers":[{"id":0,"server":"10.0.0.1:853","ssl":{"handshakes":10,"handshakes_failed":3,"verify_failures":{"expired_cert":1,"revoked_cert":1,"hostname_mismatch":1}}}]}}`, "/8/stream/upstreams", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	got, err := c.GetStreamUpstreams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.SSL{
+		Handshakes:       10,
+		HandshakesFailed: 3,
+		VerifyFailures:   ngx.VerifyFailures{ExpiredCert: 1, RevokedCert: 1, HostnameMismatch: 1},
+	}
+	peers := got["dns_backend"].Peers
+	if len(peers) != 1 {
+		t.Fatalf("want 1 peer, got %d", len(peers))
+	}
+	if !cmp.Equal(want, peers[0].SSL) {
+		t.Error(cmp.Diff(want, peers[0].SSL))
+	}
+}
+
+func TestGetUpstreams_UsesAPIVersionOfRequest(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(`{}`, "/7/http/upstreams", t)
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	if _, err := c.GetUpstreams(context.Background(), ngx.WithAPIVersion(7)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetLocationZones_ChecksAPIVersionOfRequest(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	_, err := c.GetLocationZones(context.Background(), ngx.WithAPIVersion(4))
+	if !errors.Is(err, ngx.ErrUnsupportedAPIVersion) {
+		t.Fatalf("want ErrUnsupportedAPIVersion, got %v", err)
+	}
+}
+
+func TestUpdateStreamServers_UsesConfiguredDefaultServerPort(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/8/stream/upstreams/dns_backend/servers" {
+			t.Errorf("want no changes, got %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[{"id":1,"server":"10.0.0.1:53"}]`))
+	}))
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithDefaultServerPort("53"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := []ngx.StreamUpstreamServer{{Server: "10.0.0.1"}}
+	added, deleted, updated, err := c.UpdateStreamServers(context.Background(), "dns_backend", servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 || len(deleted) != 0 || len(updated) != 0 {
+		t.Errorf("want no changes, got add %v, delete %v, update %v", added, deleted, updated)
+	}
+}
+
+func TestNewClient_FailsOnInvalidDefaultServerPort(t *testing.T) {
+	t.Parallel()
+	_, err := ngx.NewClient("http://localhost/api", ngx.WithDefaultServerPort("http"))
+	if err == nil {
+		t.Fatal("want error on invalid port, got nil")
+	}
+}
+
+func TestGetUpstreams_ReturnsAPIErrorReportedByNGINX(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(responsePathNotFound))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	_, err := c.GetUpstreams(context.Background())
+	var apiErr *ngx.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("want APIError, got %v", err)
+	}
+	want := &ngx.APIError{
+		Status:    404,
+		Text:      "path not found",
+		Code:      "PathNotFound",
+		RequestID: "f0a5a1f1e2b3c4d5e6f7a8b9c0d1e2f3",
+		Href:      "https://nginx.org/en/docs/http/ngx_http_api_module.html",
+	}
+	if !cmp.Equal(want, apiErr) {
+		t.Error(cmp.Diff(want, apiErr))
+	}
+}
+
+func TestAddKeyValPair_ReturnsAPIErrorReportedByNGINX(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"status":404,"text":"keyval not found","code":"KeyvalNotFound"},"request_id":"1b2c3d"}`))
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.AddKeyValPair(context.Background(), "zone_one", "key", "val")
+	var apiErr *ngx.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "KeyvalNotFound" {
+		t.Fatalf("want APIError with KeyvalNotFound code, got %v", err)
+	}
+}
+
+func TestGetUpstreams_ReturnsRequestErrorWithRequestContext(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "7a8b9c")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	_, err := c.GetUpstreams(context.Background())
+	var reqErr *ngx.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("want RequestError, got %v", err)
+	}
+	if reqErr.Method != http.MethodGet || reqErr.URL != ts.URL+"/8/http/upstreams" ||
+		reqErr.StatusCode != http.StatusServiceUnavailable || reqErr.RequestID != "7a8b9c" {
+		t.Errorf("want request context of failed request, got %+v", reqErr)
+	}
+}
+
+func TestDeleteKeyValPairs_ReturnsRequestErrorOnConnectionFailure(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	c := newNginxTestClient(ts.URL, t)
+	err := c.DeleteKeyValPairs(context.Background(), "zone_one")
+	var reqErr *ngx.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("want RequestError, got %v", err)
+	}
+	if reqErr.Method != http.MethodDelete || reqErr.URL != ts.URL+"/8/http/keyvals/zone_one/" {
+		t.Errorf("want request context of failed request, got %+v", reqErr)
+	}
+}
+
+// newConnCountingTestServer returns a test server that counts
+// the client connections it accepts.
+func newConnCountingTestServer(h http.HandlerFunc, conns *int32) *httptest.Server {
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(conns, 1)
+		}
+	}
+	ts.Start()
+	return ts
+}
+
+func TestClient_ReusesConnectionsAcrossRequests(t *testing.T) {
+	t.Parallel()
+	var conns int32
+	ts := newConnCountingTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"key":"val"}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(responsePathNotFound))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(responseGetNGINXInfo))
+		}
+	}, &conns)
+	defer ts.Close()
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithHTTPClient(&http.Client{Transport: &http.Transport{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := c.AddKeyValPair(ctx, "zone_one", "key", "val"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.DeleteKeyValPairs(ctx, "zone_one"); err == nil {
+			t.Fatal("want error on delete, got nil")
+		}
+		if _, err := c.GetNginxInfo(ctx); err == nil {
+			t.Fatal("want error on get, got nil")
+		}
+	}
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("want 1 connection, got %d", got)
+	}
+}
+
+var (
+	responseSupportedAPIVersions  = `[1,2,3,4,5,6,7,8]`
+	responseGetItems              = `["nginx","processes","connections","slabs","http","stream","resolvers","ssl"]`
+	responseGetNGINXInfo          = `{"version":"1.21.6","build":"nginx-plus-r27","address":"192.168.160.2","generation":1,"load_timestamp":"2022-09-24T11:28:33.668Z","timestamp":"2022-09-24T11:38:27.614Z","pid":8,"ppid":1}`
+	responseGetNGINXStatusVersion = `{"version":"1.21.6"}`
+	responseGetLicense            = `{"active_till":1749772800,"eval":false,"reporting":{"healthy":true,"fails":0,"grace":15552000}}`
+	responseGetWorkers            = `[{"id":0,"pid":3211,"connections":{"accepted":10,"dropped":0,"active":2,"idle":1},"http":{"requests":{"total":52,"current":1}}}]`
+	responseGetConnections        = `{"accepted":9,"dropped":0,"active":1,"idle":0}`
+	responsePathNotFound          = `{"error":{"status":404,"text":"path not found","code":"PathNotFound"},"request_id":"f0a5a1f1e2b3c4d5e6f7a8b9c0d1e2f3","href":"https://nginx.org/en/docs/http/ngx_http_api_module.html"}`
+	responseGetSSL                = `{"handshakes":79,"handshakes_failed":21,"session_reuses":15,"no_common_protocol":4,"no_common_cipher":2,"handshake_timeout":1,"peer_rejected_cert":3,"verify_failures":{"no_cert":1,"expired_cert":2,"revoked_cert":0,"hostname_mismatch":5,"other":0}}`
+
+	responseGetStreamZoneSyncStatus = `{"bytes_in":1024,"msgs_in":8,"msgs_out":4,"bytes_out":512,"nodes_online":2}`
+	responseGetStreamZoneSyncZones  = `{"zone_test_sync":{"records_pending":1,"records_total":10}}`
 )
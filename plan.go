@@ -0,0 +1,111 @@
+package ngx
+
+import (
+	"context"
+	"fmt"
+)
+
+// Plan represents changes of servers of an HTTP upstream computed against
+// the NGINX configuration of the given generation. Plans are serializable
+// to JSON, so they can be reviewed before they are applied with ApplyPlan.
+type Plan struct {
+	Upstream   string           `json:"upstream"`
+	Add        []UpstreamServer `json:"add"`
+	Delete     []UpstreamServer `json:"delete"`
+	Update     []UpstreamServer `json:"update"`
+	Generation int              `json:"generation"`
+}
+
+// StreamPlan represents changes of servers of a stream upstream computed
+// against the NGINX configuration of the given generation. Stream plans
+// are applied with ApplyStreamPlan.
+type StreamPlan struct {
+	Upstream   string                 `json:"upstream"`
+	Add        []StreamUpstreamServer `json:"add"`
+	Delete     []StreamUpstreamServer `json:"delete"`
+	Update     []StreamUpstreamServer `json:"update"`
+	Generation int                    `json:"generation"`
+}
+
+// PlanHTTPServers computes the changes UpdateHTTPServers would make
+// to servers of the upstream, without making them.
+func (c Client) PlanHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer) (Plan, error) {
+	info, err := c.GetNginxInfo(ctx)
+	if err != nil {
+		return Plan{}, fmt.Errorf("planning servers of %v upstream: %w", upstream, err)
+	}
+	serversInNginx, err := c.GetHTTPServers(ctx, upstream)
+	if err != nil {
+		return Plan{}, fmt.Errorf("planning servers of %v upstream: %w", upstream, err)
+	}
+	toAdd, toDelete, toUpdate, err := DetermineServerUpdates(servers, serversInNginx, WithDefaultPort(c.serverPort()))
+	if err != nil {
+		return Plan{}, fmt.Errorf("planning servers of %v upstream: %w", upstream, err)
+	}
+	return Plan{
+		Upstream:   upstream,
+		Add:        nonNil(toAdd),
+		Delete:     nonNil(toDelete),
+		Update:     nonNil(toUpdate),
+		Generation: info.Generation,
+	}, nil
+}
+
+// ApplyPlan makes the changes of the plan in NGINX verbatim. It fails with
+// ErrConfigReloaded without making any changes, if the NGINX configuration
+// generation is different than the generation the plan was computed against.
+func (c Client) ApplyPlan(ctx context.Context, plan Plan) error {
+	if err := c.checkGeneration(ctx, plan.Generation); err != nil {
+		return fmt.Errorf("applying plan of %v upstream: %w", plan.Upstream, err)
+	}
+	if err := c.applyHTTPServers(ctx, plan.Upstream, plan.Add, plan.Delete, plan.Update, updateConfig{}); err != nil {
+		return fmt.Errorf("applying plan of %v upstream: %w", plan.Upstream, err)
+	}
+	return nil
+}
+
+// PlanStreamServers computes the changes UpdateStreamServers would make
+// to servers of the stream upstream, without making them.
+func (c Client) PlanStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer) (StreamPlan, error) {
+	info, err := c.GetNginxInfo(ctx)
+	if err != nil {
+		return StreamPlan{}, fmt.Errorf("planning stream servers of %v upstream: %w", upstream, err)
+	}
+	serversInNginx, err := c.GetStreamServers(ctx, upstream)
+	if err != nil {
+		return StreamPlan{}, fmt.Errorf("planning stream servers of %v upstream: %w", upstream, err)
+	}
+	toAdd, toDelete, toUpdate, err := DetermineStreamServerUpdates(servers, serversInNginx, WithDefaultPort(c.serverPort()))
+	if err != nil {
+		return StreamPlan{}, fmt.Errorf("planning stream servers of %v upstream: %w", upstream, err)
+	}
+	return StreamPlan{
+		Upstream:   upstream,
+		Add:        nonNil(toAdd),
+		Delete:     nonNil(toDelete),
+		Update:     nonNil(toUpdate),
+		Generation: info.Generation,
+	}, nil
+}
+
+// ApplyStreamPlan makes the changes of the stream plan in NGINX verbatim.
+// It fails with ErrConfigReloaded without making any changes, if the NGINX
+// configuration generation is different than the generation of the plan.
+func (c Client) ApplyStreamPlan(ctx context.Context, plan StreamPlan) error {
+	if err := c.checkGeneration(ctx, plan.Generation); err != nil {
+		return fmt.Errorf("applying plan of %v stream upstream: %w", plan.Upstream, err)
+	}
+	if err := c.applyStreamServers(ctx, plan.Upstream, plan.Add, plan.Delete, plan.Update, updateConfig{}); err != nil {
+		return fmt.Errorf("applying plan of %v stream upstream: %w", plan.Upstream, err)
+	}
+	return nil
+}
+
+// nonNil returns an empty slice instead of nil, so planned changes
+// are always encoded as JSON arrays.
+func nonNil[S ~[]E, E any](s S) S {
+	if s == nil {
+		return S{}
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package ngx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestPlanHTTPServers_EncodesPlanAsStableJSON(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	c := newNginxTestClient(ts.URL, t)
+	plan, err := c.PlanHTTPServers(context.Background(), "test", []ngx.UpstreamServer{{Server: "10.0.0.2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"upstream":"test","add":[{"server":"10.0.0.2:80"}],"delete":[{"id":1,"server":"10.0.0.1:80"}],"update":[],"generation":0}`
+	if want != string(got) {
+		t.Error(cmp.Diff(want, string(got)))
+	}
+	if f.Mutations() != 0 {
+		t.Error("want no changes made while planning")
+	}
+}
+
+func TestApplyPlan_AppliesReviewedPlan(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t, ngx.UpstreamServer{Server: "10.0.0.1:80"})
+
+	var plan ngx.Plan
+	reviewed := `{"upstream":"test","add":[{"server":"10.0.0.2:80"}],"delete":[{"id":1,"server":"10.0.0.1:80"}],"update":[],"generation":0}`
+	if err := json.Unmarshal([]byte(reviewed), &plan); err != nil {
+		t.Fatal(err)
+	}
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ApplyPlan(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.2:80"}
+	if got := f.Servers(); !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestApplyPlan_FailsOnPlanComputedForOtherGeneration(t *testing.T) {
+	t.Parallel()
+	f, ts := newFakeUpstream("up", t)
+
+	c := newNginxTestClient(ts.URL, t)
+	plan := ngx.Plan{Upstream: "test", Add: []ngx.UpstreamServer{{Server: "10.0.0.2:80"}}, Generation: 3}
+	if err := c.ApplyPlan(context.Background(), plan); !errors.Is(err, ngx.ErrConfigReloaded) {
+		t.Fatalf("want ErrConfigReloaded, got %v", err)
+	}
+	if f.Mutations() != 0 {
+		t.Error("want no changes made for stale plan")
+	}
+}
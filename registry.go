@@ -0,0 +1,78 @@
+package ngx
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+)
+
+// Registry holds named clients of NGINX instances, so tools working with
+// many instances can address them by name, like "edge-eu-1", instead
+// of their URLs. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+// NewRegistry constructs a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{clients: make(map[string]*Client)}
+}
+
+// Add adds the client to the registry under the given name.
+func (r *Registry) Add(name string, c *Client) error {
+	if name == "" {
+		return errors.New("empty client name")
+	}
+	if c == nil {
+		return errors.New("nil client")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clients[name]; ok {
+		return fmt.Errorf("client %s already registered", name)
+	}
+	r.clients[name] = c
+	return nil
+}
+
+// Get returns the client registered under the given name.
+func (r *Registry) Get(name string) (*Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("client %s not registered", name)
+	}
+	return c, nil
+}
+
+// Names returns the names of all registered clients in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns an iterator over registered clients. It yields the name
+// and the client in sorted order of names.
+func (r *Registry) All() iter.Seq2[string, *Client] {
+	return func(yield func(string, *Client) bool) {
+		for _, name := range r.Names() {
+			c, err := r.Get(name)
+			if err != nil {
+				continue
+			}
+			if !yield(name, c) {
+				return
+			}
+		}
+	}
+}
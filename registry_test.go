@@ -0,0 +1,53 @@
+package ngx_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestRegistry_ReturnsClientsByName(t *testing.T) {
+	t.Parallel()
+	r := ngx.NewRegistry()
+	eu := newNginxTestClient("http://edge-eu-1/api", t)
+	us := newNginxTestClient("http://edge-us-1/api", t)
+	if err := r.Add("edge-us-1", us); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("edge-eu-1", eu); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.Get("edge-eu-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != eu {
+		t.Errorf("want client %s, got %s", eu.URL, got.URL)
+	}
+
+	var names []string
+	for name := range r.All() {
+		names = append(names, name)
+	}
+	want := []string{"edge-eu-1", "edge-us-1"}
+	if !cmp.Equal(want, names) {
+		t.Error(cmp.Diff(want, names))
+	}
+}
+
+func TestRegistry_FailsOnDuplicateAndUnknownNames(t *testing.T) {
+	t.Parallel()
+	r := ngx.NewRegistry()
+	c := newNginxTestClient("http://edge-eu-1/api", t)
+	if err := r.Add("edge-eu-1", c); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("edge-eu-1", c); err == nil {
+		t.Error("want error on duplicate name, got nil")
+	}
+	if _, err := r.Get("edge-us-1"); err == nil {
+		t.Error("want error on unknown name, got nil")
+	}
+}
@@ -0,0 +1,158 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ResetHTTPRequests resets the total and current counters of HTTP requests.
+func (c Client) ResetHTTPRequests(ctx context.Context) error {
+	if err := c.delete(ctx, "http/requests", http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting HTTP requests stats: %w", err)
+	}
+	return nil
+}
+
+// ResetConnections resets the accepted and dropped client connections counters.
+func (c Client) ResetConnections(ctx context.Context) error {
+	if err := c.delete(ctx, "connections", http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting connections stats: %w", err)
+	}
+	return nil
+}
+
+// ResetSSL resets the SSL handshake and session reuse counters.
+func (c Client) ResetSSL(ctx context.Context) error {
+	if err := c.delete(ctx, "ssl", http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting SSL stats: %w", err)
+	}
+	return nil
+}
+
+// ResetProcesses resets the counter of abnormally terminated and respawned child processes.
+func (c Client) ResetProcesses(ctx context.Context) error {
+	if err := c.delete(ctx, "processes", http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting processes stats: %w", err)
+	}
+	return nil
+}
+
+// ResetServerZone resets the requests and responses counters of the HTTP server zone.
+func (c Client) ResetServerZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("http/server_zones/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting server zone %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetAllServerZones resets the requests and responses counters of all HTTP server zones.
+func (c Client) ResetAllServerZones(ctx context.Context) error {
+	if err := c.delete(ctx, "http/server_zones", http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting server zones stats: %w", err)
+	}
+	return nil
+}
+
+// ResetStreamServerZone resets the connections and sessions counters of the stream server zone.
+func (c Client) ResetStreamServerZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("stream/server_zones/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting stream server zone %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetUpstreamStats resets the statistics of peers of the HTTP upstream, like requests, fails and downtime counters.
+func (c Client) ResetUpstreamStats(ctx context.Context, upstream string) error {
+	if upstream == "" {
+		return errors.New("missing upstream")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("http/upstreams/%v", upstream), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting upstream %v: %w", upstream, err)
+	}
+	return nil
+}
+
+// ResetStreamUpstreamStats resets the statistics of peers of the stream upstream, like connections, fails and downtime counters.
+func (c Client) ResetStreamUpstreamStats(ctx context.Context, upstream string) error {
+	if upstream == "" {
+		return errors.New("missing upstream")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("stream/upstreams/%v", upstream), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting stream upstream %v: %w", upstream, err)
+	}
+	return nil
+}
+
+// ResetSlab resets the slots reqs and fails counters of the shared memory zone.
+func (c Client) ResetSlab(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("slabs/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting slab %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetCacheStats resets the hit, miss and other counters of the cache zone.
+func (c Client) ResetCacheStats(ctx context.Context, cache string) error {
+	if cache == "" {
+		return errors.New("missing cache zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("http/caches/%v", cache), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting cache %v: %w", cache, err)
+	}
+	return nil
+}
+
+// ResetHTTPLimitReqZone resets the passed, delayed and rejected counters of the HTTP requests limit zone.
+func (c Client) ResetHTTPLimitReqZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("http/limit_reqs/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting limit requests zone %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetHTTPLimitConnZone resets the passed and rejected counters of the HTTP connections limit zone.
+func (c Client) ResetHTTPLimitConnZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("http/limit_conns/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting limit connections zone %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetStreamLimitConnZone resets the passed and rejected counters of the stream connections limit zone.
+func (c Client) ResetStreamLimitConnZone(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("stream/limit_conns/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting stream limit connections zone %v: %w", zone, err)
+	}
+	return nil
+}
+
+// ResetResolverStats resets the requests and responses counters of the resolver zone.
+func (c Client) ResetResolverStats(ctx context.Context, zone string) error {
+	if zone == "" {
+		return errors.New("missing zone")
+	}
+	if err := c.delete(ctx, fmt.Sprintf("resolvers/%v", zone), http.StatusNoContent); err != nil {
+		return fmt.Errorf("ngx: resetting resolver %v: %w", zone, err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package ngx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newResetTestServer returns a test server that answers DELETE
+// requests to the given path with the 204 No Content status and
+// fails the test on any other request.
+func newResetTestServer(wantPath string, t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != wantPath {
+			t.Errorf("want DELETE %s, got %s %s", wantPath, r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestResetHTTPRequests_DeletesHTTPRequestsStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/requests/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetHTTPRequests(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetHTTPRequests_FailsOnUnexpectedStatus(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(`{}`, t)
+	defer ts.Close()
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetHTTPRequests(context.Background()); err == nil {
+		t.Fatal("want error on unexpected response status, got nil")
+	}
+}
+
+func TestResetConnections_DeletesConnectionsStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/connections/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetConnections(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetSSL_DeletesSSLStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/ssl/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetSSL(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetProcesses_DeletesProcessesStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/processes/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetProcesses(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetServerZone_DeletesServerZoneStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/server_zones/zone_one/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetServerZone(context.Background(), "zone_one"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetServerZone_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetServerZone(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetAllServerZones_DeletesAllServerZonesStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/server_zones/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetAllServerZones(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetStreamServerZone_DeletesStreamServerZoneStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/stream/server_zones/dns_tcp/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetStreamServerZone(context.Background(), "dns_tcp"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetStreamServerZone_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetStreamServerZone(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetUpstreamStats_DeletesUpstreamStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/upstreams/backend/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetUpstreamStats(context.Background(), "backend"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetUpstreamStats_FailsOnMissingUpstream(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetUpstreamStats(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing upstream, got nil")
+	}
+}
+
+func TestResetStreamUpstreamStats_DeletesStreamUpstreamStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/stream/upstreams/dns_backend/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetStreamUpstreamStats(context.Background(), "dns_backend"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetStreamUpstreamStats_FailsOnMissingUpstream(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetStreamUpstreamStats(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing upstream, got nil")
+	}
+}
+
+func TestResetSlab_DeletesSlabStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/slabs/zone_one/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetSlab(context.Background(), "zone_one"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetSlab_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetSlab(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetCacheStats_DeletesCacheStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/caches/http_cache/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetCacheStats(context.Background(), "http_cache"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetCacheStats_FailsOnMissingCache(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetCacheStats(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing cache zone, got nil")
+	}
+}
+
+func TestResetHTTPLimitReqZone_DeletesLimitReqZoneStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/limit_reqs/limit_one/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetHTTPLimitReqZone(context.Background(), "limit_one"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetHTTPLimitReqZone_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetHTTPLimitReqZone(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetHTTPLimitConnZone_DeletesLimitConnZoneStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/http/limit_conns/addr/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetHTTPLimitConnZone(context.Background(), "addr"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetHTTPLimitConnZone_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetHTTPLimitConnZone(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetStreamLimitConnZone_DeletesLimitConnZoneStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/stream/limit_conns/addr/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetStreamLimitConnZone(context.Background(), "addr"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetStreamLimitConnZone_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetStreamLimitConnZone(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
+
+func TestResetResolverStats_DeletesResolverStats(t *testing.T) {
+	t.Parallel()
+	ts := newResetTestServer("/8/resolvers/resolver_one/", t)
+	c := newNginxTestClient(ts.URL, t)
+	if err := c.ResetResolverStats(context.Background(), "resolver_one"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetResolverStats_FailsOnMissingZone(t *testing.T) {
+	t.Parallel()
+	c := newNginxTestClient("http://localhost/api", t)
+	if err := c.ResetResolverStats(context.Background(), ""); err == nil {
+		t.Fatal("want error on missing zone, got nil")
+	}
+}
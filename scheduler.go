@@ -0,0 +1,147 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Sample represents a stats section collected by the Scheduler.
+type Sample struct {
+	StatsSection
+	Time time.Time
+	URL  string
+}
+
+// Sink receives samples collected by the Scheduler. Each section is
+// collected in its own goroutine, so Write is called concurrently
+// for different sections and sinks must synchronize writes.
+type Sink interface {
+	Write(Sample)
+}
+
+// SinkFunc is an adapter to allow the use of ordinary functions as sinks.
+type SinkFunc func(Sample)
+
+// Write calls f(s).
+func (f SinkFunc) Write(s Sample) {
+	f(s)
+}
+
+// Scheduler collects stats sections from NGINX, each section at its own
+// interval, and feeds the samples to the registered sinks. For example,
+// connections can be collected every 5 seconds and caches every minute.
+type Scheduler struct {
+	client    *Client
+	sinks     []Sink
+	intervals map[string]time.Duration
+	mu        sync.Mutex
+}
+
+// NewScheduler takes the client and the sinks and constructs a new scheduler.
+func NewScheduler(c *Client, sinks ...Sink) (*Scheduler, error) {
+	if c == nil {
+		return nil, errors.New("nil client")
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("no sinks")
+	}
+	return &Scheduler{
+		client:    c,
+		sinks:     sinks,
+		intervals: make(map[string]time.Duration),
+	}, nil
+}
+
+// Every schedules collecting the named stats sections at the given interval.
+// Section names are the Section constants or names of registered custom
+// sections. Scheduling a section again replaces its interval. Every is safe
+// to call while Run is running, but the change takes effect on the next Run.
+func (s *Scheduler) Every(d time.Duration, sections ...string) error {
+	if d <= 0 {
+		return errors.New("interval must be positive")
+	}
+	known := make(map[string]bool)
+	for _, ss := range s.client.statsSections() {
+		known[ss.name] = true
+	}
+	for _, name := range sections {
+		if !known[name] {
+			return fmt.Errorf("unknown stats section %s", name)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range sections {
+		s.intervals[name] = d
+	}
+	return nil
+}
+
+// Run collects the scheduled sections, each one immediately and then
+// at its interval, until ctx is done. It returns the ctx error.
+func (s *Scheduler) Run(ctx context.Context) error {
+	intervals := s.scheduled()
+	if len(intervals) == 0 {
+		return errors.New("no scheduled sections")
+	}
+	var wg sync.WaitGroup
+	for _, section := range s.client.statsSections() {
+		d, ok := intervals[section.name]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(section statsSection, d time.Duration) {
+			defer wg.Done()
+			ticker := s.client.clock().NewTicker(d)
+			defer ticker.Stop()
+			for {
+				s.collect(ctx, section)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C():
+				}
+			}
+		}(section, d)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// scheduled returns a copy of the intervals of the scheduled sections.
+func (s *Scheduler) scheduled() map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	intervals := make(map[string]time.Duration, len(s.intervals))
+	for name, d := range s.intervals {
+		intervals[name] = d
+	}
+	return intervals
+}
+
+func (s *Scheduler) collect(ctx context.Context, section statsSection) {
+	v, err := section.fetch(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		v = nil
+	}
+	sample := Sample{
+		StatsSection: StatsSection{Name: section.name, Value: v, Err: err},
+		Time:         s.client.clock().Now(),
+		URL:          s.client.URL,
+	}
+	s.mu.Lock()
+	sinks := slices.Clone(s.sinks)
+	s.mu.Unlock()
+	for _, sink := range sinks {
+		sink.Write(sample)
+	}
+}
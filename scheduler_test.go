@@ -0,0 +1,142 @@
+package ngx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qba73/ngx"
+)
+
+func TestScheduler_CollectsScheduledSectionsAtTheirIntervals(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	sink := ngx.SinkFunc(func(s ngx.Sample) {
+		if s.Err != nil {
+			t.Errorf("section %s: %v", s.Name, s.Err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		counts[s.Name]++
+	})
+	s, err := ngx.NewScheduler(newNginxTestClient(ts.URL, t), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(10*time.Millisecond, ngx.SectionConnections); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(time.Hour, ngx.SectionCaches); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := s.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+	if counts[ngx.SectionConnections] < 2 {
+		t.Errorf("want connections collected at least twice, got %d", counts[ngx.SectionConnections])
+	}
+	if counts[ngx.SectionCaches] != 1 {
+		t.Errorf("want caches collected once, got %d", counts[ngx.SectionCaches])
+	}
+}
+
+func TestScheduler_SchedulesSectionsWhileRunning(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	s, err := ngx.NewScheduler(newNginxTestClient(ts.URL, t), ngx.SinkFunc(func(ngx.Sample) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(time.Millisecond, ngx.SectionConnections); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	for ctx.Err() == nil {
+		if err := s.Every(time.Millisecond, ngx.SectionCaches); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestScheduler_CollectsOtherSectionsWhileSinkBlocks(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	connections := make(chan struct{}, 10)
+	sink := ngx.SinkFunc(func(s ngx.Sample) {
+		if s.Name == ngx.SectionCaches {
+			<-ctx.Done()
+			return
+		}
+		select {
+		case connections <- struct{}{}:
+		default:
+		}
+	})
+	s, err := ngx.NewScheduler(newNginxTestClient(ts.URL, t), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(time.Hour, ngx.SectionCaches); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(time.Millisecond, ngx.SectionConnections); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	for range 3 {
+		select {
+		case <-connections:
+		case <-time.After(time.Second):
+			t.Fatal("want connections collected while caches sink blocks")
+		}
+	}
+	if err := s.Every(time.Minute, ngx.SectionSlabs); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestScheduler_FailsOnUnknownSection(t *testing.T) {
+	t.Parallel()
+	s, err := ngx.NewScheduler(newNginxTestClient("http://localhost", t), ngx.SinkFunc(func(ngx.Sample) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Every(time.Second, "bogus"); err == nil {
+		t.Fatal("want error on unknown section, got nil")
+	}
+}
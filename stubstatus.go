@@ -0,0 +1,116 @@
+package ngx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StubStatus represents basic status information of open-source NGINX
+// reported by the ngx_http_stub_status_module.
+//
+// https://nginx.org/en/docs/http/ngx_http_stub_status_module.html
+type StubStatus struct {
+	Connections StubConnections
+	Requests    uint64
+}
+
+// StubConnections represents client connections stats of open-source NGINX.
+type StubConnections struct {
+	Active   uint64
+	Accepted uint64
+	Handled  uint64
+	Reading  uint64
+	Writing  uint64
+	Waiting  uint64
+}
+
+// StubStatusClient lets you access the stub_status page of open-source NGINX.
+type StubStatusClient struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewStubStatusClient takes the URL of the NGINX stub_status page,
+// for example "http://localhost/nginx_status", and constructs a new
+// default client.
+func NewStubStatusClient(statusURL string) (*StubStatusClient, error) {
+	if statusURL == "" {
+		return nil, errors.New("empty status URL string")
+	}
+	return &StubStatusClient{
+		URL:        statusURL,
+		HTTPClient: http.DefaultClient,
+	}, nil
+}
+
+// GetStubStatus fetches and parses the stub_status page.
+func (c StubStatusClient) GetStubStatus(ctx context.Context) (StubStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return StubStatus{}, fmt.Errorf("ngx: creating stub status request: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return StubStatus{}, fmt.Errorf("ngx: getting stub status: %w", err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return StubStatus{}, fmt.Errorf("ngx: getting stub status: unexpected response status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return StubStatus{}, fmt.Errorf("ngx: reading stub status: %w", err)
+	}
+	status, err := parseStubStatus(string(body))
+	if err != nil {
+		return StubStatus{}, fmt.Errorf("ngx: parsing stub status: %w", err)
+	}
+	return status, nil
+}
+
+// parseStubStatus parses the plain text of the stub_status page:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseStubStatus(text string) (StubStatus, error) {
+	fields := strings.Fields(text)
+	labels := map[int]string{
+		0: "Active", 1: "connections:", 3: "server", 4: "accepts", 5: "handled", 6: "requests",
+		10: "Reading:", 12: "Writing:", 14: "Waiting:",
+	}
+	if len(fields) != 16 {
+		return StubStatus{}, fmt.Errorf("unexpected number of fields %d", len(fields))
+	}
+	for i, label := range labels {
+		if fields[i] != label {
+			return StubStatus{}, fmt.Errorf("unexpected field %q, want %q", fields[i], label)
+		}
+	}
+	var values [7]uint64
+	for i, pos := range []int{2, 7, 8, 9, 11, 13, 15} {
+		v, err := strconv.ParseUint(fields[pos], 10, 64)
+		if err != nil {
+			return StubStatus{}, fmt.Errorf("parsing %q: %w", fields[pos], err)
+		}
+		values[i] = v
+	}
+	s := StubStatus{
+		Connections: StubConnections{
+			Active:   values[0],
+			Accepted: values[1],
+			Handled:  values[2],
+			Reading:  values[4],
+			Writing:  values[5],
+			Waiting:  values[6],
+		},
+		Requests: values[3],
+	}
+	return s, nil
+}
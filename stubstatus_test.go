@@ -0,0 +1,58 @@
+package ngx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qba73/ngx"
+)
+
+func TestGetStubStatus_ParsesStubStatusPage(t *testing.T) {
+	t.Parallel()
+	ts := newTestServerWithPathValidator(responseStubStatus, "/nginx_status", t)
+	defer ts.Close()
+
+	c, err := ngx.NewStubStatusClient(ts.URL + "/nginx_status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetStubStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ngx.StubStatus{
+		Connections: ngx.StubConnections{
+			Active:   291,
+			Accepted: 16630948,
+			Handled:  16630946,
+			Reading:  6,
+			Writing:  179,
+			Waiting:  106,
+		},
+		Requests: 31070465,
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetStubStatus_FailsOnUnexpectedPage(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(`{"version":"1.21.6"}`, t)
+	defer ts.Close()
+
+	c, err := ngx.NewStubStatusClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetStubStatus(context.Background()); err == nil {
+		t.Fatal("want error on invalid stub status page, got nil")
+	}
+}
+
+var responseStubStatus = `Active connections: 291 
+server accepts handled requests
+ 16630948 16630946 31070465 
+Reading: 6 Writing: 179 Waiting: 106 
+`
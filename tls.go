@@ -0,0 +1,148 @@
+package ngx
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithTLSConfig is a func option that configures the Client to use
+// the TLS configuration, for example with a custom CA pool, when it talks
+// to HTTPS API endpoints. The configuration is applied to a copy of
+// the transport of the HTTP client.
+func WithTLSConfig(cfg *tls.Config) option {
+	return func(c *Client) error {
+		if cfg == nil {
+			return errors.New("nil TLS config")
+		}
+		c.tlsConfig = cfg.Clone()
+		c.tlsChanged = true
+		return nil
+	}
+}
+
+// WithClientCertFiles is a func option that configures the Client to
+// authenticate to the API with the client certificate and key read from
+// the given PEM files. The Client reloads the certificate when the files
+// change or the certificate expires, so short-lived certificates issued
+// by tools like Vault or cert-manager are picked up without recreating
+// the Client. It replaces the certificate configured with
+// WithClientCertificate.
+func WithClientCertFiles(certPath, keyPath string) option {
+	return func(c *Client) error {
+		if certPath == "" || keyPath == "" {
+			return errors.New("empty client certificate or key path")
+		}
+		r := &certReloader{certPath: certPath, keyPath: keyPath}
+		if _, err := r.certificate(); err != nil {
+			return err
+		}
+		c.certReloader = r
+		c.clientCert = nil
+		c.tlsChanged = true
+		return nil
+	}
+}
+
+// WithClientCertificate is a func option that configures the Client
+// to authenticate to the API with the client certificate and key,
+// for mutual TLS. The certificate and key are PEM encoded, for example
+// read from a secret store. It replaces the certificate configured
+// with WithClientCertFiles.
+func WithClientCertificate(certPEM, keyPEM []byte) option {
+	return func(c *Client) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("loading client certificate: %w", err)
+		}
+		c.clientCert = &cert
+		c.certReloader = nil
+		c.tlsChanged = true
+		return nil
+	}
+}
+
+// configureTLS applies the TLS options to a copy of the Client's
+// HTTP client, so the transport passed with WithHTTPClient,
+// or the default one, isn't modified. It does nothing unless
+// a TLS option was applied, so clones share the HTTP client.
+// The client certificate is kept when only the TLS config changes.
+func (c *Client) configureTLS() error {
+	if !c.tlsChanged {
+		return nil
+	}
+	c.tlsChanged = false
+	var tr *http.Transport
+	switch t := c.HTTPClient.Transport.(type) {
+	case nil:
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		tr = t.Clone()
+	default:
+		return fmt.Errorf("configuring TLS: unsupported transport type %T", t)
+	}
+	if c.tlsConfig != nil {
+		tr.TLSClientConfig = c.tlsConfig.Clone()
+	}
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	}
+	if c.clientCert != nil {
+		tr.TLSClientConfig.Certificates = []tls.Certificate{*c.clientCert}
+		tr.TLSClientConfig.GetClientCertificate = nil
+	}
+	if c.certReloader != nil {
+		tr.TLSClientConfig.Certificates = nil
+		tr.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.certReloader.certificate()
+		}
+	}
+	hc := *c.HTTPClient
+	hc.Transport = tr
+	c.HTTPClient = &hc
+	return nil
+}
+
+// certReloader loads the client certificate from files
+// and reloads it when the files change or it expires.
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+func (r *certReloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client key: %w", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certTime) && keyInfo.ModTime().Equal(r.keyTime) && !expired(r.cert) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certTime = certInfo.ModTime()
+	r.keyTime = keyInfo.ModTime()
+	return r.cert, nil
+}
+
+func expired(cert *tls.Certificate) bool {
+	return cert.Leaf != nil && time.Now().After(cert.Leaf.NotAfter)
+}
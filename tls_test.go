@@ -0,0 +1,213 @@
+package ngx_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qba73/ngx"
+)
+
+// writeClientCert writes a self-signed client certificate
+// with the given common name and its key to PEM files.
+func writeClientCert(t *testing.T, certPath, keyPath, name string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{certPath, keyPath} {
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWithClientCertFiles_ReloadsRotatedCertificate(t *testing.T) {
+	t.Parallel()
+	var gotName string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	now := time.Now()
+	writeClientCert(t, certPath, keyPath, "client-1", now.Add(-time.Minute))
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithHTTPClient(ts.Client()), ngx.WithClientCertFiles(certPath, keyPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotName != "client-1" {
+		t.Errorf("want client certificate client-1, got %q", gotName)
+	}
+
+	writeClientCert(t, certPath, keyPath, "client-2", now)
+	ts.CloseClientConnections()
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotName != "client-2" {
+		t.Errorf("want rotated client certificate client-2, got %q", gotName)
+	}
+}
+
+func TestWithClientCertFiles_FailsOnMissingFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	_, err := ngx.NewClient("http://localhost/api", ngx.WithClientCertFiles(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")))
+	if err == nil {
+		t.Fatal("want error on missing certificate files, got nil")
+	}
+}
+
+func TestWithTLSConfig_TrustsCustomCAPool(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	c, err := ngx.NewClient(ts.URL, ngx.WithTLSConfig(&tls.Config{RootCAs: pool}), ngx.WithHTTPClient(&http.Client{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithTLSConfig_DoesNotModifyDefaultHTTPClient(t *testing.T) {
+	t.Parallel()
+	c, err := ngx.NewClient("https://localhost/api", ngx.WithTLSConfig(&tls.Config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HTTPClient == http.DefaultClient {
+		t.Error("want copy of default HTTP client, got default HTTP client")
+	}
+}
+
+func TestWithClientCertificate_AuthenticatesWithClientCertificate(t *testing.T) {
+	t.Parallel()
+	var gotName string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	writeClientCert(t, certPath, keyPath, "client-1", time.Now())
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithClientCertificate(certPEM, keyPEM), ngx.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotName != "client-1" {
+		t.Errorf("want client certificate client-1, got %q", gotName)
+	}
+}
+
+func TestWithClientCertificate_FailsOnInvalidKeyPair(t *testing.T) {
+	t.Parallel()
+	_, err := ngx.NewClient("https://localhost/api", ngx.WithClientCertificate([]byte("cert"), []byte("key")))
+	if err == nil {
+		t.Fatal("want error on invalid key pair, got nil")
+	}
+}
+
+func TestClone_KeepsClientCertificateWithNewTLSConfig(t *testing.T) {
+	t.Parallel()
+	var gotName string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotName = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(responseGetNGINXInfo))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	writeClientCert(t, certPath, keyPath, "client-1", time.Now())
+
+	c, err := ngx.NewClient(ts.URL, ngx.WithClientCertFiles(certPath, keyPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	clone, err := c.Clone(ngx.WithTLSConfig(&tls.Config{RootCAs: pool}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clone.GetNginxInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gotName != "client-1" {
+		t.Errorf("want client certificate client-1, got %q", gotName)
+	}
+}